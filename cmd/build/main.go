@@ -1,25 +1,46 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/ForestEckhardt/yarn-cnb/yarn"
 	"github.com/cloudfoundry/packit"
 	"github.com/cloudfoundry/packit/cargo"
+	"github.com/cloudfoundry/packit/fs"
+	"github.com/cloudfoundry/packit/pexec"
 	"github.com/cloudfoundry/packit/postal"
 	"github.com/cloudfoundry/packit/scribe"
 )
 
 func main() {
-	logger := scribe.NewLogger(os.Stdout)
-	logEmitter := yarn.NewLogEmitter(logger)
+	secrets, err := yarn.FindSecrets(os.Environ(), yarn.ServiceBindingRoot())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read service bindings: %s\n", err)
+		os.Exit(1)
+	}
+
+	redactingWriter := yarn.NewRedactingWriter(os.Stdout, secrets)
+	logger := scribe.NewLogger(redactingWriter)
+	logEmitter := yarn.NewLogEmitter(logger).WithLevel(os.Getenv("BP_LOG_LEVEL"))
 
 	transport := cargo.NewTransport()
 	dependencyService := postal.NewService(transport)
 
 	clock := yarn.NewClock(time.Now)
 	cacheHandler := yarn.NewCacheHandler()
+	checksumCalculator := fs.NewChecksumCalculator()
+	integrityChecker := yarn.NewIntegrityChecker(checksumCalculator)
+	nodeExecutable := pexec.NewExecutable("node")
+	lockfileParser := yarn.NewLockfileParser()
+	registryChecker := yarn.NewRegistryChecker()
+	mirrorVerifier := yarn.NewMirrorVerifier()
+	sbomWriter := yarn.NewSBOMWriter()
+	licenseScanner := yarn.NewLicenseScanner()
+	advisoryAuditor := yarn.NewAdvisoryAuditor()
+	lockfilePolicy := yarn.NewLockfilePolicy(registryChecker, mirrorVerifier, licenseScanner, advisoryAuditor, logEmitter)
+	buildpackYMLParser := yarn.NewBuildpackYMLParser()
 
-	packit.Build(yarn.Build(dependencyService, cacheHandler, clock, logEmitter))
+	packit.Build(yarn.RedactErrors(yarn.WithBuildReport(yarn.Build(dependencyService, cacheHandler, integrityChecker, checksumCalculator, nodeExecutable, lockfileParser, sbomWriter, lockfilePolicy, buildpackYMLParser, clock, logEmitter), logEmitter), redactingWriter))
 }
@@ -8,6 +8,7 @@ import (
 func main() {
 	packageJSONParser := yarn.NewPackageJSONParser()
 	buildpackYMLParser := yarn.NewBuildpackYMLParser()
+	nodeVersionFileParser := yarn.NewNodeVersionFileParser()
 
-	packit.Detect(yarn.Detect(packageJSONParser, buildpackYMLParser))
+	packit.Detect(yarn.Detect(packageJSONParser, buildpackYMLParser, nodeVersionFileParser))
 }
@@ -4,6 +4,8 @@ go 1.13
 
 require (
 	cloud.google.com/go v0.53.0 // indirect
+	github.com/BurntSushi/toml v0.3.1
+	github.com/Masterminds/semver v1.5.0
 	github.com/cloudfoundry/dagger v0.0.0-20200213200846-c2a9723f08c4
 	github.com/cloudfoundry/libcfbuildpack v1.91.23 // indirect
 	github.com/cloudfoundry/occam v0.0.0-20200218193031-7e2052ce2f0f
@@ -93,7 +93,9 @@ func testCaching(t *testing.T, context spec.G, it spec.S) {
 
 			splitLogs := GetBuildLogs(logs.String())
 			Expect(splitLogs).To(ContainSequence([]interface{}{
-				fmt.Sprintf("Yarn Buildpack %s", "0.0.0"),
+				"    Other versions available:",
+				MatchRegexp(`      1\.\d+\.\d+`),
+				"",
 				"  Reusing cached layer /layers/org.cloudfoundry.yarn/yarn",
 				"",
 			},
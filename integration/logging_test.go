@@ -58,10 +58,28 @@ func testLogging(t *testing.T, context spec.G, it spec.S) {
 			splitLogs := GetBuildLogs(logs.String())
 			Expect(splitLogs).To(ContainSequence([]interface{}{
 				fmt.Sprintf("Yarn Buildpack %s", "0.0.0"),
+				"    Warning: buildpack.yml is deprecated and will be removed in a future version, set these environment variables instead:",
+				`      BP_YARN_VERSION="*"`,
+				"",
+				"  Resolving Yarn version",
+				MatchRegexp(`    Selected Yarn version \(using buildpack\.yml\): 1\.\d+\.\d+`),
+				"      constraint     -> *",
+				MatchRegexp(`      sha256         -> [0-9a-f]{64}`),
+				MatchRegexp(`      stacks         -> .*io\.buildpacks\.stacks\.bionic`),
+				"      version source -> buildpack.yml",
+				"",
+				"    Other versions available:",
+				MatchRegexp(`      1\.\d+\.\d+`),
+				"",
 				"  Executing build process",
+				"    No cached layer",
 				MatchRegexp(`    Installing Yarn 1\.\d+\.\d+`),
 				MatchRegexp(`      Completed in (\d+\.\d+|\d{3})`),
 				"",
+				"  Build timings",
+				MatchRegexp(`    resolve               \d`),
+				MatchRegexp(`    download and extract  \d`),
+				"",
 			}))
 		})
 	})
@@ -0,0 +1,309 @@
+package yarn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// AdvisoryBindingType is the type of a service binding that provides an OSV
+// advisory database.
+const AdvisoryBindingType = "osv-advisories"
+
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "unknown"
+	SeverityLow      Severity = "low"
+	SeverityModerate Severity = "moderate"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Severities lists every severity from the most to the least severe.
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityModerate, SeverityLow, SeverityUnknown}
+
+// ParseSeverity reads a severity name, ignoring case. "medium" is accepted as
+// an alias of moderate. Unknown is only assigned to advisories that record no
+// severity, so it is not accepted.
+func ParseSeverity(value string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(value)))
+	if severity == "medium" {
+		return SeverityModerate, nil
+	}
+
+	for _, s := range Severities {
+		if severity == s && s != SeverityUnknown {
+			return s, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown severity %q: must be one of low, moderate, high or critical", value)
+}
+
+// AtLeast reports whether the severity is as severe as or more severe than
+// the threshold.
+func (s Severity) AtLeast(threshold Severity) bool {
+	return s.rank() >= threshold.rank()
+}
+
+func (s Severity) rank() int {
+	for i, severity := range Severities {
+		if s == severity {
+			return len(Severities) - i
+		}
+	}
+
+	return 0
+}
+
+type AuditPolicy struct {
+	Advisories string `yaml:"advisories"`
+	Threshold  string `yaml:"threshold"`
+}
+
+type AuditFinding struct {
+	ID       string
+	Summary  string
+	Severity Severity
+	Name     string
+	Version  string
+	Line     int
+}
+
+type AuditReport struct {
+	Packages int
+	Findings []AuditFinding
+}
+
+type AdvisoryAuditor struct{}
+
+func NewAdvisoryAuditor() AdvisoryAuditor {
+	return AdvisoryAuditor{}
+}
+
+// Audit matches the resolved lockfile packages against every OSV advisory
+// found in the database directory. Findings are ordered from the most to the
+// least severe.
+func (a AdvisoryAuditor) Audit(databasePath string, entries []LockfileEntry) (AuditReport, error) {
+	advisories, err := loadAdvisories(databasePath)
+	if err != nil {
+		return AuditReport{}, fmt.Errorf("failed to audit packages: %w", err)
+	}
+
+	report := AuditReport{Packages: len(entries)}
+	for _, entry := range entries {
+		for _, advisory := range advisories[entry.Name] {
+			if !advisory.affects(entry.Name, entry.Version) {
+				continue
+			}
+
+			report.Findings = append(report.Findings, AuditFinding{
+				ID:       advisory.ID,
+				Summary:  advisory.Summary,
+				Severity: advisory.severity(),
+				Name:     entry.Name,
+				Version:  entry.Version,
+				Line:     entry.Line,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Severity.rank() > report.Findings[j].Severity.rank()
+	})
+
+	return report, nil
+}
+
+type osvAdvisory struct {
+	ID       string `json:"id"`
+	Summary  string `json:"summary"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions          []string `json:"versions"`
+		EcosystemSpecific struct {
+			Severity string `json:"severity"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// loadAdvisories reads every JSON file below the database directory and
+// indexes the advisories by the npm packages they affect. Hidden directories
+// are skipped so that the ..data directory of a mounted binding is not read
+// twice.
+func loadAdvisories(databasePath string) (map[string][]osvAdvisory, error) {
+	advisories := map[string][]osvAdvisory{}
+	seen := map[string]bool{}
+
+	err := filepath.Walk(databasePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != databasePath && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var advisory osvAdvisory
+		err = json.Unmarshal(content, &advisory)
+		if err != nil {
+			return fmt.Errorf("failed to parse advisory %s: %w", path, err)
+		}
+
+		if seen[advisory.ID] {
+			return nil
+		}
+		seen[advisory.ID] = true
+
+		names := map[string]bool{}
+		for _, affected := range advisory.Affected {
+			if affected.Package.Ecosystem == "npm" && !names[affected.Package.Name] {
+				names[affected.Package.Name] = true
+				advisories[affected.Package.Name] = append(advisories[affected.Package.Name], advisory)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return advisories, nil
+}
+
+// affects reports whether the given package version is listed by the
+// advisory, either explicitly or by one of its SEMVER or ECOSYSTEM ranges.
+func (a osvAdvisory) affects(name, version string) bool {
+	v, versionErr := semver.NewVersion(version)
+
+	for _, affected := range a.Affected {
+		if affected.Package.Ecosystem != "npm" || affected.Package.Name != name {
+			continue
+		}
+
+		for _, listed := range affected.Versions {
+			if listed == version {
+				return true
+			}
+		}
+
+		if versionErr != nil {
+			continue
+		}
+
+		for _, r := range affected.Ranges {
+			if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+				continue
+			}
+
+			type event struct {
+				kind    string
+				version *semver.Version
+			}
+
+			var events []event
+			for _, e := range r.Events {
+				kind, value := "introduced", e.Introduced
+				switch {
+				case e.Fixed != "":
+					kind, value = "fixed", e.Fixed
+				case e.LastAffected != "":
+					kind, value = "last_affected", e.LastAffected
+				}
+
+				if value == "0" {
+					value = "0.0.0-0"
+				}
+
+				parsed, err := semver.NewVersion(value)
+				if err != nil {
+					continue
+				}
+
+				events = append(events, event{kind: kind, version: parsed})
+			}
+
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].version.LessThan(events[j].version)
+			})
+
+			vulnerable := false
+			for _, e := range events {
+				switch e.kind {
+				case "introduced":
+					if !v.LessThan(e.version) {
+						vulnerable = true
+					}
+				case "fixed":
+					if !v.LessThan(e.version) {
+						vulnerable = false
+					}
+				case "last_affected":
+					if v.GreaterThan(e.version) {
+						vulnerable = false
+					}
+				}
+			}
+
+			if vulnerable {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// severity reads the qualitative severity recorded by the advisory database,
+// such as the one written by the GitHub Advisory Database. Advisories that
+// only carry a CVSS vector are reported with an unknown severity.
+func (a osvAdvisory) severity() Severity {
+	values := []string{a.DatabaseSpecific.Severity}
+	for _, affected := range a.Affected {
+		values = append(values, affected.EcosystemSpecific.Severity)
+	}
+
+	for _, value := range values {
+		severity, err := ParseSeverity(value)
+		if err == nil {
+			return severity
+		}
+	}
+
+	return SeverityUnknown
+}
@@ -0,0 +1,245 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testAdvisoryAuditor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		databaseDir string
+		entries     []yarn.LockfileEntry
+		auditor     yarn.AdvisoryAuditor
+	)
+
+	it.Before(func() {
+		var err error
+		databaseDir, err = ioutil.TempDir("", "advisories")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(databaseDir, "npm"), os.ModePerm)).To(Succeed())
+
+		err = ioutil.WriteFile(filepath.Join(databaseDir, "npm", "GHSA-aaaa.json"), []byte(`{
+			"id": "GHSA-aaaa",
+			"summary": "Prototype pollution",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "lodash"},
+				"ranges": [{
+					"type": "SEMVER",
+					"events": [{"introduced": "0"}, {"fixed": "4.17.19"}]
+				}]
+			}],
+			"database_specific": {"severity": "HIGH"}
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(databaseDir, "npm", "GHSA-bbbb.json"), []byte(`{
+			"id": "GHSA-bbbb",
+			"summary": "Regular expression denial of service",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "@scope/parser"},
+				"ranges": [{
+					"type": "SEMVER",
+					"events": [{"introduced": "2.0.0"}, {"last_affected": "2.3.0"}]
+				}]
+			}],
+			"database_specific": {"severity": "MODERATE"}
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(databaseDir, "npm", "GHSA-cccc.json"), []byte(`{
+			"id": "GHSA-cccc",
+			"summary": "Malicious release",
+			"affected": [{
+				"package": {"ecosystem": "npm", "name": "leftpad"},
+				"versions": ["1.3.0"]
+			}]
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(databaseDir, "npm", "PYSEC-dddd.json"), []byte(`{
+			"id": "PYSEC-dddd",
+			"affected": [{
+				"package": {"ecosystem": "PyPI", "name": "lodash"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+			}]
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(databaseDir, "type"), []byte("osv-advisories"), 0644)).To(Succeed())
+
+		entries = []yarn.LockfileEntry{
+			{Name: "lodash", Version: "4.17.15", Line: 5},
+			{Name: "@scope/parser", Version: "2.3.0", Line: 10},
+			{Name: "@scope/parser", Version: "2.3.1", Line: 15},
+			{Name: "leftpad", Version: "1.3.0", Line: 20},
+			{Name: "express", Version: "4.17.1", Line: 25},
+		}
+
+		auditor = yarn.NewAdvisoryAuditor()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(databaseDir)).To(Succeed())
+	})
+
+	it("reports the affected packages from the most to the least severe", func() {
+		report, err := auditor.Audit(databaseDir, entries)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report).To(Equal(yarn.AuditReport{
+			Packages: 5,
+			Findings: []yarn.AuditFinding{
+				{
+					ID:       "GHSA-aaaa",
+					Summary:  "Prototype pollution",
+					Severity: yarn.SeverityHigh,
+					Name:     "lodash",
+					Version:  "4.17.15",
+					Line:     5,
+				},
+				{
+					ID:       "GHSA-bbbb",
+					Summary:  "Regular expression denial of service",
+					Severity: yarn.SeverityModerate,
+					Name:     "@scope/parser",
+					Version:  "2.3.0",
+					Line:     10,
+				},
+				{
+					ID:       "GHSA-cccc",
+					Summary:  "Malicious release",
+					Severity: yarn.SeverityUnknown,
+					Name:     "leftpad",
+					Version:  "1.3.0",
+					Line:     20,
+				},
+			},
+		}))
+	})
+
+	context("when a package is installed under an alias", func() {
+		it.Before(func() {
+			err := ioutil.WriteFile(filepath.Join(databaseDir, "npm", "GHSA-eeee.json"), []byte(`{
+				"id": "GHSA-eeee",
+				"summary": "Regular expression denial of service",
+				"affected": [{
+					"package": {"ecosystem": "npm", "name": "string-width"},
+					"ranges": [{
+						"type": "SEMVER",
+						"events": [{"introduced": "0"}, {"fixed": "5.0.0"}]
+					}]
+				}],
+				"database_specific": {"severity": "HIGH"}
+			}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			lockfile, err := ioutil.TempFile("", "yarn.lock")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(lockfile.Name())
+
+			_, err = lockfile.WriteString(`__metadata:
+  version: 6
+  cacheKey: 8
+
+"string-width-cjs@npm:string-width@^4.2.0":
+  version: 4.2.3
+  resolution: "string-width@npm:4.2.3"
+  languageName: node
+  linkType: hard
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lockfile.Close()).To(Succeed())
+
+			entries, err = yarn.NewLockfileParser().Parse(lockfile.Name())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("matches advisories for the resolved package", func() {
+			report, err := auditor.Audit(databaseDir, entries)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Findings).To(Equal([]yarn.AuditFinding{
+				{
+					ID:       "GHSA-eeee",
+					Summary:  "Regular expression denial of service",
+					Severity: yarn.SeverityHigh,
+					Name:     "string-width",
+					Version:  "4.2.3",
+					Line:     5,
+				},
+			}))
+		})
+	})
+
+	context("when the database is empty", func() {
+		it("reports no findings", func() {
+			emptyDir, err := ioutil.TempDir("", "advisories")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(emptyDir)
+
+			report, err := auditor.Audit(emptyDir, entries)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Packages).To(Equal(5))
+			Expect(report.Findings).To(BeEmpty())
+		})
+	})
+
+	context("ParseSeverity", func() {
+		it("parses severities ignoring case", func() {
+			severity, err := yarn.ParseSeverity("CRITICAL")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(severity).To(Equal(yarn.SeverityCritical))
+
+			severity, err = yarn.ParseSeverity("medium")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(severity).To(Equal(yarn.SeverityModerate))
+		})
+
+		it("orders severities", func() {
+			Expect(yarn.SeverityHigh.AtLeast(yarn.SeverityModerate)).To(BeTrue())
+			Expect(yarn.SeverityHigh.AtLeast(yarn.SeverityHigh)).To(BeTrue())
+			Expect(yarn.SeverityLow.AtLeast(yarn.SeverityModerate)).To(BeFalse())
+			Expect(yarn.SeverityUnknown.AtLeast(yarn.SeverityLow)).To(BeFalse())
+		})
+
+		context("when the severity is unknown", func() {
+			it("returns an error", func() {
+				_, err := yarn.ParseSeverity("severe")
+				Expect(err).To(MatchError(`unknown severity "severe": must be one of low, moderate, high or critical`))
+
+				_, err = yarn.ParseSeverity("unknown")
+				Expect(err).To(MatchError(`unknown severity "unknown": must be one of low, moderate, high or critical`))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when an advisory is malformed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(databaseDir, "npm", "broken.json"), []byte(`%%%`), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := auditor.Audit(databaseDir, entries)
+				Expect(err).To(MatchError(ContainSubstring("failed to audit packages: failed to parse advisory")))
+			})
+		})
+
+		context("when the database does not exist", func() {
+			it("returns an error", func() {
+				_, err := auditor.Audit(filepath.Join(databaseDir, "missing"), entries)
+				Expect(err).To(MatchError(ContainSubstring("failed to audit packages:")))
+			})
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package yarn
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/postal"
+)
+
+// yarnBOMEntry describes the installed yarn dependency. The lifecycle records
+// the buildpack plan entries returned from build as the bill of materials for
+// the image, so BOM entries are reported as plan entries.
+func yarnBOMEntry(entry packit.BuildpackPlanEntry, dependency postal.Dependency, licenses []string) packit.BuildpackPlanEntry {
+	metadata := map[string]interface{}{}
+	for key, value := range entry.Metadata {
+		metadata[key] = value
+	}
+
+	metadata["launch"] = true
+	metadata["sha256"] = dependency.SHA256
+	metadata["uri"] = dependency.URI
+	metadata["source"] = dependency.Source
+	metadata["licenses"] = licenses
+
+	return packit.BuildpackPlanEntry{
+		Name:     entry.Name,
+		Version:  dependency.Version,
+		Metadata: metadata,
+	}
+}
+
+func lockfileBOMEntries(entries []LockfileEntry) []packit.BuildpackPlanEntry {
+	var bom []packit.BuildpackPlanEntry
+	for _, entry := range entries {
+		metadata := map[string]interface{}{
+			"launch": true,
+		}
+
+		if entry.Resolution != "" {
+			metadata["resolution"] = entry.Resolution
+			metadata["checksum"] = entry.Checksum
+		} else {
+			metadata["resolved"] = entry.Resolved
+			metadata["integrity"] = entry.Integrity
+		}
+
+		bom = append(bom, packit.BuildpackPlanEntry{
+			Name:     entry.Name,
+			Version:  entry.Version,
+			Metadata: metadata,
+		})
+	}
+
+	return bom
+}
+
+// parseDependencyLicenses reads the licenses of the given dependency from
+// buildpack.toml, which the postal.Dependency type does not carry.
+func parseDependencyLicenses(path string, dependency postal.Dependency) ([]string, error) {
+	var buildpack struct {
+		Metadata struct {
+			Dependencies []struct {
+				ID       string   `toml:"id"`
+				Version  string   `toml:"version"`
+				Licenses []string `toml:"licenses"`
+			} `toml:"dependencies"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(path, &buildpack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	for _, d := range buildpack.Metadata.Dependencies {
+		if d.ID == dependency.ID && d.Version == dependency.Version {
+			return d.Licenses, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -1,17 +1,56 @@
 package yarn
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/pexec"
 	"github.com/cloudfoundry/packit/postal"
 )
 
 //go:generate faux --interface CacheMatcher --output fakes/cache_matcher.go
 type CacheMatcher interface {
-	Match(metadata map[string]interface{}, key, sha string) bool
+	Match(metadata LayerMetadata, key CacheKey) (bool, CacheMismatch)
+}
+
+//go:generate faux --interface LayerValidator --output fakes/layer_validator.go
+type LayerValidator interface {
+	Validate(layerPath string, metadata LayerMetadata) error
+}
+
+//go:generate faux --interface YarnLockParser --output fakes/yarn_lock_parser.go
+type YarnLockParser interface {
+	Parse(path string) ([]LockfileEntry, error)
+}
+
+//go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
+type SBOMGenerator interface {
+	Generate(workingDir, layerPath string, entries []LockfileEntry, info packit.BuildpackInfo, created time.Time) error
+}
+
+//go:generate faux --interface PolicyEnforcer --output fakes/policy_enforcer.go
+type PolicyEnforcer interface {
+	Enforce(workingDir string, entries []LockfileEntry, config Config) error
+}
+
+//go:generate faux --interface BuildpackConfigParser --output fakes/buildpack_config_parser.go
+type BuildpackConfigParser interface {
+	Parse(path string) (Config, error)
+}
+
+//go:generate faux --interface Summer --output fakes/summer.go
+type Summer interface {
+	Sum(path string) (string, error)
+}
+
+//go:generate faux --interface Executable --output fakes/executable.go
+type Executable interface {
+	Execute(execution pexec.Execution) error
 }
 
 //go:generate faux --interface DependencyService --output fakes/dependency_service.go
@@ -20,23 +59,83 @@ type DependencyService interface {
 	Install(dependency postal.Dependency, cnbPath, layerPath string) error
 }
 
-func Build(dependencyService DependencyService, cacheMatcher CacheMatcher, clock Clock, logEmitter LogEmitter) packit.BuildFunc {
+func Build(dependencyService DependencyService, cacheMatcher CacheMatcher, layerValidator LayerValidator, summer Summer, nodeExecutable Executable, lockfileParser YarnLockParser, sbomGenerator SBOMGenerator, policyEnforcer PolicyEnforcer, configParser BuildpackConfigParser, clock Clock, logEmitter LogEmitter) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logEmitter.BuildpackTitle(context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 
-		yarnLayer, err := context.Layers.Get("yarn", packit.LaunchLayer)
+		config, err := configParser.Parse(filepath.Join(context.WorkingDir, "buildpack.yml"))
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if variables := config.EnvironmentVariables(); len(variables) > 0 {
+			logEmitter.DeprecatedBuildpackYML(variables)
+		}
+
+		epoch, reproducible, err := sourceDateEpoch()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		buildTime := clock.Now()
+		if reproducible {
+			buildTime = epoch
+		}
+
+		// The layer is cached so that the lifecycle restores its contents,
+		// which are validated before the layer is reused.
+		yarnLayer, err := context.Layers.Get("yarn", packit.LaunchLayer, packit.CacheLayer)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		//TODO:Write a dep resolver
-		dependency, err := dependencyService.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), "yarn", "*", context.Stack)
+		logEmitter.DebugPlanEntries(context.Plan.Entries)
+
+		then := clock.Now()
+
+		version, versionSource := yarnPlanVersion(context.Plan.Entries)
+
+		dependency, err := dependencyService.Resolve(filepath.Join(context.CNBPath, "buildpack.toml"), "yarn", version, context.Stack)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		if !cacheMatcher.Match(yarnLayer.Metadata, "cache_sha", dependency.SHA256) {
+		candidates, err := listDependencies(filepath.Join(context.CNBPath, "buildpack.toml"), "yarn", context.Stack)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		logEmitter.DebugCandidates(PlanDependencyYarn, version, context.Stack, candidates)
+
+		logEmitter.PhaseTime("resolve", clock.Now().Sub(then))
+
+		logEmitter.ResolvedDependency(dependency, version, versionSource, candidates)
+		logEmitter.SelectedDependency(PlanDependencyYarn, dependency.Version, version, versionSource)
+
+		cacheKey := CacheKey{
+			SHA:              dependency.SHA256,
+			BuildpackVersion: context.BuildpackInfo.Version,
+			Stack:            context.Stack,
+		}
+
+		logEmitter.DebugCacheKey(cacheKey)
+
+		metadata := ParseLayerMetadata(yarnLayer.Metadata)
+
+		match, mismatch := cacheMatcher.Match(metadata, cacheKey)
+
+		var contentsErr error
+		if match {
+			contentsErr = layerValidator.Validate(yarnLayer.Path, metadata)
+		}
+
+		if !match || contentsErr != nil {
 			logEmitter.Logger.Process("Executing build process")
+			if contentsErr != nil {
+				logEmitter.LayerContentsInvalid(contentsErr)
+			} else {
+				logEmitter.CacheInvalidated(mismatch)
+			}
 
 			err = yarnLayer.Reset()
 			if err != nil {
@@ -45,31 +144,147 @@ func Build(dependencyService DependencyService, cacheMatcher CacheMatcher, clock
 
 			logEmitter.Logger.Subprocess("Installing Yarn %s", dependency.Version)
 
-			then := clock.Now()
+			then = clock.Now()
 
 			err = dependencyService.Install(dependency, context.CNBPath, yarnLayer.Path)
 			if err != nil {
 				return packit.BuildResult{}, err
 			}
 
-			logEmitter.CompletionTime(then)
+			logEmitter.CompletionTime("download and extract", clock.Now().Sub(then))
 
-			yarnLayer.Metadata = map[string]interface{}{
-				"built_at":  clock.Now().Format(time.RFC3339Nano),
-				"cache_sha": dependency.SHA256,
+			if reproducible {
+				err = normalizeModTimes(yarnLayer.Path, epoch)
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("failed to normalize yarn layer modification times: %w", err)
+				}
 			}
 
+			contentChecksum, err := summer.Sum(filepath.Join(yarnLayer.Path, "bin"))
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to checksum yarn layer: %w", err)
+			}
+
+			logEmitter.DebugCommand(context.WorkingDir, "node", "--version")
+
+			nodeVersion, err := parseNodeVersion(nodeExecutable, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logEmitter.SelectedDependency(PlanDependencyNode, nodeVersion, "", "node --version")
+
+			lockfileChecksum, err := sumLockfile(summer, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			yarnLayer.Metadata = LayerMetadata{
+				SchemaVersion:    LayerMetadataSchemaVersion,
+				BuiltAt:          buildTime.Format(time.RFC3339Nano),
+				DependencySHA:    dependency.SHA256,
+				YarnVersion:      dependency.Version,
+				Stack:            context.Stack,
+				BuildpackVersion: context.BuildpackInfo.Version,
+				NodeVersion:      nodeVersion,
+				LockfileChecksum: lockfileChecksum,
+				ContentChecksum:  contentChecksum,
+			}.Map()
+
 			//TODO:Add logging
 			yarnLayer.SharedEnv.Append("PATH", yarnLayer.Path, string(os.PathListSeparator))
+
+			logEmitter.DebugEnvironment(yarnLayer.Name, yarnLayer.SharedEnv)
 		} else {
 			logEmitter.ReusingLayer(yarnLayer.Path)
+			logEmitter.SelectedDependency(PlanDependencyNode, metadata.NodeVersion, "", "layer metadata")
+		}
+
+		licenses, err := parseDependencyLicenses(filepath.Join(context.CNBPath, "buildpack.toml"), dependency)
+		if err != nil {
+			return packit.BuildResult{}, err
 		}
 
+		var entries []packit.BuildpackPlanEntry
+		for _, entry := range context.Plan.Entries {
+			if entry.Name == PlanDependencyYarn {
+				entry = yarnBOMEntry(entry, dependency, licenses)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		var sbomLayers []packit.Layer
+
+		lockfilePath := filepath.Join(context.WorkingDir, "yarn.lock")
+		_, err = os.Stat(lockfilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return packit.BuildResult{}, err
+		}
+
+		lockfileExists := err == nil
+
+		var lockfileEntries []LockfileEntry
+		if lockfileExists {
+			lockfileEntries, err = lockfileParser.Parse(lockfilePath)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		err = policyEnforcer.Enforce(context.WorkingDir, lockfileEntries, config)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if lockfileExists {
+			entries = append(entries, lockfileBOMEntries(lockfileEntries)...)
+
+			sbomLayer, err := context.Layers.Get("sbom", packit.LaunchLayer)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			err = sbomLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			then = clock.Now()
+
+			err = sbomGenerator.Generate(context.WorkingDir, sbomLayer.Path, lockfileEntries, context.BuildpackInfo, buildTime)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logEmitter.PhaseTime("sbom", clock.Now().Sub(then))
+
+			if reproducible {
+				err = normalizeModTimes(sbomLayer.Path, epoch)
+				if err != nil {
+					return packit.BuildResult{}, fmt.Errorf("failed to normalize sbom layer modification times: %w", err)
+				}
+			}
+
+			sbomLayers = append(sbomLayers, sbomLayer)
+		}
+
+		// Wall-clock timings would make the metadata of otherwise identical
+		// builds differ, so reproducible builds only keep them in the report.
+		if reproducible {
+			delete(yarnLayer.Metadata, metadataKeyPhaseTimings)
+		} else {
+			if yarnLayer.Metadata == nil {
+				yarnLayer.Metadata = map[string]interface{}{}
+			}
+			yarnLayer.Metadata[metadataKeyPhaseTimings] = logEmitter.PhaseTimings()
+		}
+
+		logEmitter.PhaseSummary()
+
 		return packit.BuildResult{
-			Plan: context.Plan,
-			Layers: []packit.Layer{
-				yarnLayer,
-			},
+			Plan:   packit.BuildpackPlan{Entries: entries},
+			Layers: append([]packit.Layer{yarnLayer}, sbomLayers...),
 			Processes: []packit.Process{
 				{
 					Type:    "web",
@@ -79,3 +294,57 @@ func Build(dependencyService DependencyService, cacheMatcher CacheMatcher, clock
 		}, nil
 	}
 }
+
+// yarnPlanVersion returns the version constraint of the yarn entry in the
+// buildpack plan, which is set from buildpack.yml or BP_YARN_VERSION during
+// detect, along with where it came from. Any version is selected when no
+// entry sets one.
+func yarnPlanVersion(entries []packit.BuildpackPlanEntry) (string, string) {
+	for _, entry := range entries {
+		if entry.Name == PlanDependencyYarn && entry.Version != "" {
+			source, _ := entry.Metadata["version-source"].(string)
+			if source == "" {
+				source = "buildpack plan"
+			}
+
+			return entry.Version, source
+		}
+	}
+
+	return "*", "default"
+}
+
+func parseNodeVersion(nodeExecutable Executable, workingDir string) (string, error) {
+	buffer := bytes.NewBuffer(nil)
+	err := nodeExecutable.Execute(pexec.Execution{
+		Args:   []string{"--version"},
+		Dir:    workingDir,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to determine node version: %w: %s", err, strings.TrimSpace(buffer.String()))
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(buffer.String()), "v"), nil
+}
+
+func sumLockfile(summer Summer, workingDir string) (string, error) {
+	path := filepath.Join(workingDir, "yarn.lock")
+
+	_, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	checksum, err := summer.Sum(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum yarn.lock: %w", err)
+	}
+
+	return checksum, nil
+}
@@ -0,0 +1,126 @@
+package yarn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/packit"
+)
+
+const (
+	// BuildReportLayer is the value of BP_YARN_BUILD_REPORT that writes the
+	// report into a build-only layer instead of a path.
+	BuildReportLayer = "layer"
+
+	BuildReportFilename = "report.json"
+)
+
+type BuildReport struct {
+	Status       string             `json:"status"`
+	Dependencies []ReportDependency `json:"dependencies"`
+	Cache        ReportCache        `json:"cache"`
+	Timings      []ReportTiming     `json:"timings"`
+	Warnings     []string           `json:"warnings"`
+}
+
+type ReportDependency struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Requested string `json:"requested,omitempty"`
+	Source    string `json:"source"`
+}
+
+type ReportCache struct {
+	Layer  string `json:"layer"`
+	Hit    bool   `json:"hit"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ReportTiming struct {
+	Phase      string `json:"phase"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func newBuildReport() *BuildReport {
+	return &BuildReport{
+		Dependencies: []ReportDependency{},
+		Timings:      []ReportTiming{},
+		Warnings:     []string{},
+	}
+}
+
+func (r *BuildReport) addTiming(phase string, duration time.Duration) {
+	r.Timings = append(r.Timings, ReportTiming{
+		Phase:      phase,
+		DurationMS: duration.Milliseconds(),
+	})
+}
+
+// WithBuildReport writes the report collected by the LogEmitter once the build
+// finishes, whether it succeeds or not. BP_YARN_BUILD_REPORT names the file to
+// write, or is set to "layer" to write it into the build-only "build-report"
+// layer of a successful build. No report is written when it is unset.
+func WithBuildReport(build packit.BuildFunc, logEmitter LogEmitter) packit.BuildFunc {
+	return func(context packit.BuildContext) (packit.BuildResult, error) {
+		path := os.Getenv("BP_YARN_BUILD_REPORT")
+		if path == "" {
+			return build(context)
+		}
+
+		result, err := build(context)
+
+		logEmitter.report.Status = "succeeded"
+		if err != nil {
+			logEmitter.report.Status = "failed"
+		}
+
+		if path == BuildReportLayer {
+			if err != nil {
+				return result, err
+			}
+
+			layer, err := context.Layers.Get("build-report", packit.BuildLayer)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			err = layer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			path = filepath.Join(layer.Path, BuildReportFilename)
+			result.Layers = append(result.Layers, layer)
+		}
+
+		writeErr := logEmitter.WriteReport(path)
+		if err != nil {
+			return result, err
+		}
+
+		if writeErr != nil {
+			return packit.BuildResult{}, fmt.Errorf("failed to write build report: %w", writeErr)
+		}
+
+		return result, nil
+	}
+}
+
+// WriteReport writes the report collected so far as JSON.
+func (e LogEmitter) WriteReport(path string) error {
+	content, err := json.MarshalIndent(e.report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
@@ -0,0 +1,167 @@
+package yarn_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBuildReport(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layersDir  string
+		reportDir  string
+		emitter    yarn.LogEmitter
+		buildError error
+		build      packit.BuildFunc
+	)
+
+	it.Before(func() {
+		var err error
+		layersDir, err = ioutil.TempDir("", "layers")
+		Expect(err).NotTo(HaveOccurred())
+
+		reportDir, err = ioutil.TempDir("", "report")
+		Expect(err).NotTo(HaveOccurred())
+
+		emitter = yarn.NewLogEmitter(scribe.NewLogger(bytes.NewBuffer(nil)))
+		buildError = nil
+
+		build = yarn.WithBuildReport(func(packit.BuildContext) (packit.BuildResult, error) {
+			emitter.SelectedDependency("yarn", "1.22.0", "*", "default")
+			emitter.SelectedDependency("node", "12.16.1", "", "node --version")
+			emitter.CacheInvalidated(yarn.CacheMismatch{
+				Reason: yarn.CacheMismatchStackChanged,
+				Key:    "stack",
+				Old:    "some-stack",
+				New:    "other-stack",
+			})
+			emitter.CompletionTime("download and extract", 1500*time.Millisecond)
+			emitter.Warning("some-warning")
+
+			return packit.BuildResult{
+				Layers: []packit.Layer{{Name: "yarn"}},
+			}, buildError
+		}, emitter)
+	})
+
+	it.After(func() {
+		Expect(os.Unsetenv("BP_YARN_BUILD_REPORT")).To(Succeed())
+		Expect(os.RemoveAll(layersDir)).To(Succeed())
+		Expect(os.RemoveAll(reportDir)).To(Succeed())
+	})
+
+	context("when BP_YARN_BUILD_REPORT is a path", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_YARN_BUILD_REPORT", filepath.Join(reportDir, "reports", "yarn.json"))).To(Succeed())
+		})
+
+		it("writes the report to that path", func() {
+			result, err := build(packit.BuildContext{Layers: packit.Layers{Path: layersDir}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Layers).To(HaveLen(1))
+
+			content, err := ioutil.ReadFile(filepath.Join(reportDir, "reports", "yarn.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var report yarn.BuildReport
+			Expect(json.Unmarshal(content, &report)).To(Succeed())
+			Expect(report.Status).To(Equal("succeeded"))
+			Expect(report.Dependencies).To(Equal([]yarn.ReportDependency{
+				{Name: "yarn", Version: "1.22.0", Requested: "*", Source: "default"},
+				{Name: "node", Version: "12.16.1", Source: "node --version"},
+			}))
+			Expect(report.Cache).To(Equal(yarn.ReportCache{
+				Layer:  "yarn",
+				Reason: "stack changed (some-stack -> other-stack)",
+			}))
+			Expect(report.Timings).To(Equal([]yarn.ReportTiming{
+				{Phase: "download and extract", DurationMS: 1500},
+			}))
+			Expect(report.Warnings).To(Equal([]string{"some-warning"}))
+		})
+
+		context("when the build fails", func() {
+			it.Before(func() {
+				buildError = errors.New("build failed")
+			})
+
+			it("still writes the report", func() {
+				_, err := build(packit.BuildContext{Layers: packit.Layers{Path: layersDir}})
+				Expect(err).To(MatchError("build failed"))
+
+				content, err := ioutil.ReadFile(filepath.Join(reportDir, "reports", "yarn.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`"status": "failed"`))
+			})
+		})
+	})
+
+	context("when BP_YARN_BUILD_REPORT is layer", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_YARN_BUILD_REPORT", "layer")).To(Succeed())
+		})
+
+		it("writes the report into a build-only layer", func() {
+			result, err := build(packit.BuildContext{Layers: packit.Layers{Path: layersDir}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Layers).To(HaveLen(2))
+			Expect(result.Layers[1].Name).To(Equal("build-report"))
+			Expect(result.Layers[1].Build).To(BeTrue())
+			Expect(result.Layers[1].Launch).To(BeFalse())
+			Expect(result.Layers[1].Cache).To(BeFalse())
+
+			Expect(filepath.Join(layersDir, "build-report", "report.json")).To(BeARegularFile())
+		})
+	})
+
+	context("when BP_YARN_BUILD_REPORT is not set", func() {
+		it("does not write a report", func() {
+			result, err := build(packit.BuildContext{Layers: packit.Layers{Path: layersDir}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(filepath.Join(layersDir, "build-report")).NotTo(BeADirectory())
+		})
+	})
+
+	context("ReusingLayer", func() {
+		it("records a cache hit", func() {
+			emitter.ReusingLayer(filepath.Join(layersDir, "yarn"))
+			Expect(emitter.WriteReport(filepath.Join(reportDir, "report.json"))).To(Succeed())
+
+			content, err := ioutil.ReadFile(filepath.Join(reportDir, "report.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`"cache": {
+    "layer": "yarn",
+    "hit": true
+  }`))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the report cannot be written", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_BUILD_REPORT", filepath.Join(reportDir, "report.json"))).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(reportDir, "report.json"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{Layers: packit.Layers{Path: layersDir}})
+				Expect(err).To(MatchError(ContainSubstring("failed to write build report:")))
+			})
+		})
+	})
+}
@@ -3,6 +3,7 @@ package yarn_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"github.com/ForestEckhardt/yarn-cnb/yarn"
 	"github.com/ForestEckhardt/yarn-cnb/yarn/fakes"
 	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/pexec"
 	"github.com/cloudfoundry/packit/postal"
 	"github.com/cloudfoundry/packit/scribe"
 	"github.com/sclevine/spec"
@@ -30,6 +32,13 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		dependencyService *fakes.DependencyService
 		cacheMatcher      *fakes.CacheMatcher
+		layerValidator    *fakes.LayerValidator
+		lockfileParser    *fakes.YarnLockParser
+		sbomGenerator     *fakes.SBOMGenerator
+		policyEnforcer    *fakes.PolicyEnforcer
+		configParser      *fakes.BuildpackConfigParser
+		summer            *fakes.Summer
+		nodeExecutable    *fakes.Executable
 		clock             yarn.Clock
 		now               time.Time
 		buffer            *bytes.Buffer
@@ -45,9 +54,19 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		workingDir, err = ioutil.TempDir("", "working-dir")
 		Expect(err).NotTo(HaveOccurred())
 
+		Expect(ioutil.WriteFile(filepath.Join(workingDir, "yarn.lock"), nil, 0644)).To(Succeed())
+
 		cnbDir, err = ioutil.TempDir("", "cnb")
 		Expect(err).NotTo(HaveOccurred())
 
+		err = ioutil.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "yarn"
+  licenses = ["some-license"]
+  version = "some-version"
+`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
 		now = time.Now()
 		clock = yarn.NewClock(func() time.Time {
 			return now
@@ -68,12 +87,48 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		}
 
 		cacheMatcher = &fakes.CacheMatcher{}
+		cacheMatcher.MatchCall.Returns.CacheMismatch = yarn.CacheMismatch{
+			Reason: yarn.CacheMismatchMissingKey,
+			Key:    "dependency_sha",
+		}
+
+		layerValidator = &fakes.LayerValidator{}
+
+		summer = &fakes.Summer{}
+		summer.SumCall.Stub = func(path string) (string, error) {
+			if filepath.Base(path) == "yarn.lock" {
+				return "some-lockfile-checksum", nil
+			}
+
+			return "some-content-checksum", nil
+		}
+
+		nodeExecutable = &fakes.Executable{}
+		nodeExecutable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "v12.16.1")
+			return nil
+		}
+
+		lockfileParser = &fakes.YarnLockParser{}
+		lockfileParser.ParseCall.Returns.LockfileEntrySlice = []yarn.LockfileEntry{
+			{
+				Name:      "some-package",
+				Version:   "1.2.3",
+				Resolved:  "some-resolved-url",
+				Integrity: "some-integrity",
+			},
+		}
+
+		sbomGenerator = &fakes.SBOMGenerator{}
 
 		buffer = bytes.NewBuffer(nil)
 
 		logger := scribe.NewLogger(buffer)
 
-		build = yarn.Build(dependencyService, cacheMatcher, clock, yarn.NewLogEmitter(logger))
+		policyEnforcer = &fakes.PolicyEnforcer{}
+		configParser = &fakes.BuildpackConfigParser{}
+
+		build = yarn.Build(dependencyService, cacheMatcher, layerValidator, summer, nodeExecutable, lockfileParser, sbomGenerator, policyEnforcer, configParser, clock, yarn.NewLogEmitter(logger))
 	})
 
 	it.After(func() {
@@ -85,6 +140,10 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 	context("when adding yarn layer to image", func() {
 		it("resolves and calls the build process", func() {
 			result, err := build(packit.BuildContext{
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Yarn Buildpack",
+					Version: "some-buildpack-version",
+				},
 				WorkingDir: workingDir,
 				CNBPath:    cnbDir,
 				Layers:     packit.Layers{Path: layersDir},
@@ -99,7 +158,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(result).To(Equal(packit.BuildResult{
 				Plan: packit.BuildpackPlan{
 					Entries: []packit.BuildpackPlanEntry{
-						{Name: "yarn"},
+						{
+							Name:    "yarn",
+							Version: "some-version",
+							Metadata: map[string]interface{}{
+								"launch":   true,
+								"sha256":   "some-sha",
+								"uri":      "some-uri",
+								"source":   "some-source",
+								"licenses": []string{"some-license"},
+							},
+						},
+						{
+							Name:    "some-package",
+							Version: "1.2.3",
+							Metadata: map[string]interface{}{
+								"launch":    true,
+								"resolved":  "some-resolved-url",
+								"integrity": "some-integrity",
+							},
+						},
 					},
 				},
 				Layers: []packit.Layer{
@@ -114,12 +192,35 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv: packit.Environment{},
 						Build:     false,
 						Launch:    true,
-						Cache:     false,
+						Cache:     true,
 						Metadata: map[string]interface{}{
-							"built_at":  timestamp,
-							"cache_sha": "some-sha",
+							"schema_version":    2,
+							"built_at":          timestamp,
+							"dependency_sha":    "some-sha",
+							"yarn_version":      "some-version",
+							"stack":             "some-stack",
+							"buildpack_version": "some-buildpack-version",
+							"node_version":      "12.16.1",
+							"lockfile_checksum": "some-lockfile-checksum",
+							"content_checksum":  "some-content-checksum",
+							"phase_timings": map[string]int64{
+								"resolve":              0,
+								"download and extract": 0,
+								"sbom":                 0,
+							},
 						},
-					}},
+					},
+					{
+						Name:      "sbom",
+						Path:      filepath.Join(layersDir, "sbom"),
+						SharedEnv: packit.Environment{},
+						BuildEnv:  packit.Environment{},
+						LaunchEnv: packit.Environment{},
+						Build:     false,
+						Launch:    true,
+						Cache:     false,
+					},
+				},
 				Processes: []packit.Process{
 					{
 						Type:    "web",
@@ -133,6 +234,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(dependencyService.ResolveCall.Receives.Version).To(Equal("*"))
 			Expect(dependencyService.ResolveCall.Receives.Stack).To(Equal("some-stack"))
 
+			Expect(cacheMatcher.MatchCall.Receives.Metadata).To(Equal(yarn.LayerMetadata{
+				SchemaVersion: 2,
+			}))
+			Expect(cacheMatcher.MatchCall.Receives.Key).To(Equal(yarn.CacheKey{
+				SHA:              "some-sha",
+				BuildpackVersion: "some-buildpack-version",
+				Stack:            "some-stack",
+			}))
+
 			Expect(dependencyService.InstallCall.Receives.Dependency).To(Equal(postal.Dependency{
 				ID:           "yarn",
 				Name:         "Yarn",
@@ -145,12 +255,246 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			}))
 			Expect(dependencyService.InstallCall.Receives.CnbPath).To(Equal(cnbDir))
 			Expect(dependencyService.InstallCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "yarn")))
+
+			Expect(lockfileParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "yarn.lock")))
+
+			Expect(policyEnforcer.EnforceCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(policyEnforcer.EnforceCall.Receives.Entries).To(Equal(lockfileParser.ParseCall.Returns.LockfileEntrySlice))
+			Expect(policyEnforcer.EnforceCall.Receives.Config).To(Equal(yarn.Config{}))
+
+			Expect(sbomGenerator.GenerateCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(sbomGenerator.GenerateCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "sbom")))
+			Expect(sbomGenerator.GenerateCall.Receives.Entries).To(Equal(lockfileParser.ParseCall.Returns.LockfileEntrySlice))
+			Expect(sbomGenerator.GenerateCall.Receives.Info).To(Equal(packit.BuildpackInfo{
+				Name:    "Yarn Buildpack",
+				Version: "some-buildpack-version",
+			}))
+			Expect(sbomGenerator.GenerateCall.Receives.Created).To(Equal(now))
+
+			Expect(nodeExecutable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"--version"}))
+			Expect(nodeExecutable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+			Expect(summer.SumCall.CallCount).To(Equal(2))
+			Expect(summer.SumCall.Receives.Path).To(Equal(filepath.Join(workingDir, "yarn.lock")))
+
+			Expect(layerValidator.ValidateCall.CallCount).To(Equal(0))
+
+			Expect(configParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "buildpack.yml")))
+
+			Expect(buffer.String()).To(ContainSubstring("Yarn Buildpack some-buildpack-version"))
+			Expect(buffer.String()).To(ContainSubstring("Selected Yarn version (using default): some-version"))
+			Expect(buffer.String()).To(ContainSubstring("Executing build process"))
+			Expect(buffer.String()).To(ContainSubstring("Cached layer invalid: metadata key missing: dependency_sha"))
+			Expect(buffer.String()).To(ContainSubstring("Build timings"))
+			Expect(buffer.String()).NotTo(ContainSubstring("[debug]"))
+			Expect(buffer.String()).NotTo(ContainSubstring("buildpack.yml is deprecated"))
+		})
+	})
+
+	context("when the debug log level is enabled", func() {
+		it.Before(func() {
+			err := ioutil.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte(`
+[[metadata.dependencies]]
+  id = "yarn"
+  sha256 = "some-sha"
+  stacks = ["some-stack"]
+  version = "1.21.1"
+
+[[metadata.dependencies]]
+  id = "yarn"
+  sha256 = "other-sha"
+  stacks = ["some-stack"]
+  version = "1.22.0"
+
+[[metadata.dependencies]]
+  id = "yarn"
+  sha256 = "other-stack-sha"
+  stacks = ["other-stack"]
+  version = "1.22.1"
+`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			logEmitter := yarn.NewLogEmitter(scribe.NewLogger(buffer)).WithLevel("debug")
+			build = yarn.Build(dependencyService, cacheMatcher, layerValidator, summer, nodeExecutable, lockfileParser, sbomGenerator, policyEnforcer, configParser, clock, logEmitter)
+		})
+
+		it("prints the plan, candidates, cache key, environment and commands", func() {
+			_, err := build(packit.BuildContext{
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Yarn Buildpack",
+					Version: "some-buildpack-version",
+				},
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{Name: "yarn", Metadata: map[string]interface{}{"launch": true}},
+					},
+				},
+				Stack: "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(ContainSubstring("[debug] Buildpack plan entries (1):"))
+			Expect(buffer.String()).To(ContainSubstring(`[debug]   yarn "" {launch=true}`))
+			Expect(buffer.String()).To(ContainSubstring(`[debug] Candidate yarn versions for "*" on some-stack (2):
+    [debug]   1.22.0 (sha256: other-sha)
+    [debug]   1.21.1 (sha256: some-sha)`))
+			Expect(buffer.String()).To(ContainSubstring("[debug] Cache key: dependency_sha=some-sha buildpack_version=some-buildpack-version stack=some-stack"))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("[debug] Running 'node --version' in %s", workingDir)))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("[debug]   PATH.append=%s", filepath.Join(layersDir, "yarn"))))
+		})
+	})
+
+	context("when there is no yarn.lock", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "yarn.lock"))).To(Succeed())
+		})
+
+		it("records an empty lockfile checksum", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("lockfile_checksum", ""))
+
+			Expect(summer.SumCall.CallCount).To(Equal(1))
+			Expect(summer.SumCall.Receives.Path).To(Equal(filepath.Join(layersDir, "yarn", "bin")))
+
+			Expect(lockfileParser.ParseCall.CallCount).To(Equal(0))
+			Expect(policyEnforcer.EnforceCall.CallCount).To(Equal(1))
+			Expect(policyEnforcer.EnforceCall.Receives.Entries).To(BeEmpty())
+			Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+			Expect(result.Layers).To(HaveLen(1))
+		})
+	})
+
+	context("when the buildpack plan requires a yarn version", func() {
+		it("resolves the required version", func() {
+			_, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+				Plan: packit.BuildpackPlan{
+					Entries: []packit.BuildpackPlanEntry{
+						{
+							Name:    "yarn",
+							Version: "1.21.x",
+							Metadata: map[string]interface{}{
+								"version-source": "BP_YARN_VERSION",
+							},
+						},
+					},
+				},
+				Stack: "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencyService.ResolveCall.Receives.Version).To(Equal("1.21.x"))
+
+			Expect(buffer.String()).To(ContainSubstring("Selected Yarn version (using BP_YARN_VERSION): some-version"))
+			Expect(buffer.String()).To(ContainSubstring("constraint     -> 1.21.x"))
+		})
+	})
+
+	context("when buildpack.yml sets configuration", func() {
+		it.Before(func() {
+			configParser.ParseCall.Returns.Config = yarn.Config{
+				Licenses: yarn.LicensePolicy{
+					Allow: []string{"MIT"},
+				},
+			}
+		})
+
+		it("warns that buildpack.yml is deprecated", func() {
+			_, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(ContainSubstring("Warning: buildpack.yml is deprecated"))
+			Expect(buffer.String()).To(ContainSubstring(`      BP_YARN_LICENSE_ALLOW="MIT"`))
+		})
+	})
+
+	context("when SOURCE_DATE_EPOCH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("SOURCE_DATE_EPOCH", "1577836800")).To(Succeed())
+
+			dependencyService.InstallCall.Stub = func(_ postal.Dependency, _, layerPath string) error {
+				err := os.MkdirAll(filepath.Join(layerPath, "bin"), os.ModePerm)
+				if err != nil {
+					return err
+				}
+
+				return ioutil.WriteFile(filepath.Join(layerPath, "bin", "yarn"), nil, 0755)
+			}
+
+			sbomGenerator.GenerateCall.Stub = func(_, layerPath string, _ []yarn.LockfileEntry, _ packit.BuildpackInfo, _ time.Time) error {
+				return ioutil.WriteFile(filepath.Join(layerPath, "sbom.cdx.json"), nil, 0644)
+			}
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("SOURCE_DATE_EPOCH")).To(Succeed())
+		})
+
+		it("uses it as the build time and normalizes the layer modification times", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				CNBPath:    cnbDir,
+				Layers:     packit.Layers{Path: layersDir},
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("built_at", "2020-01-01T00:00:00Z"))
+
+			for _, path := range []string{"yarn", filepath.Join("yarn", "bin"), filepath.Join("yarn", "bin", "yarn"), "sbom", filepath.Join("sbom", "sbom.cdx.json")} {
+				info, err := os.Stat(filepath.Join(layersDir, path))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.ModTime().UTC()).To(Equal(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)))
+			}
+		})
+
+		it("writes identical layer metadata for identical inputs", func() {
+			tick, step := time.Now(), time.Duration(0)
+			clock = yarn.NewClock(func() time.Time {
+				step += time.Second
+				tick = tick.Add(step)
+				return tick
+			})
+
+			var metadata []map[string]interface{}
+			for i := 0; i < 2; i++ {
+				build = yarn.Build(dependencyService, cacheMatcher, layerValidator, summer, nodeExecutable, lockfileParser, sbomGenerator, policyEnforcer, configParser, clock, yarn.NewLogEmitter(scribe.NewLogger(buffer)))
+
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				metadata = append(metadata, result.Layers[0].Metadata)
+			}
+
+			Expect(metadata[0]).NotTo(HaveKey("phase_timings"))
+			Expect(metadata[1]).To(Equal(metadata[0]))
+			Expect(buffer.String()).To(ContainSubstring("Build timings"))
 		})
 	})
 
 	context("when re-using previous yarn layer", func() {
 		it.Before(func() {
 			cacheMatcher.MatchCall.Returns.Bool = true
+			cacheMatcher.MatchCall.Returns.CacheMismatch = yarn.CacheMismatch{}
 		})
 
 		it("does not redo the build process", func() {
@@ -169,7 +513,26 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(result).To(Equal(packit.BuildResult{
 				Plan: packit.BuildpackPlan{
 					Entries: []packit.BuildpackPlanEntry{
-						{Name: "yarn"},
+						{
+							Name:    "yarn",
+							Version: "some-version",
+							Metadata: map[string]interface{}{
+								"launch":   true,
+								"sha256":   "some-sha",
+								"uri":      "some-uri",
+								"source":   "some-source",
+								"licenses": []string{"some-license"},
+							},
+						},
+						{
+							Name:    "some-package",
+							Version: "1.2.3",
+							Metadata: map[string]interface{}{
+								"launch":    true,
+								"resolved":  "some-resolved-url",
+								"integrity": "some-integrity",
+							},
+						},
 					},
 				},
 				Layers: []packit.Layer{
@@ -181,6 +544,22 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 						LaunchEnv: packit.Environment{},
 						Build:     false,
 						Launch:    true,
+						Cache:     true,
+						Metadata: map[string]interface{}{
+							"phase_timings": map[string]int64{
+								"resolve": 0,
+								"sbom":    0,
+							},
+						},
+					},
+					{
+						Name:      "sbom",
+						Path:      filepath.Join(layersDir, "sbom"),
+						SharedEnv: packit.Environment{},
+						BuildEnv:  packit.Environment{},
+						LaunchEnv: packit.Environment{},
+						Build:     false,
+						Launch:    true,
 						Cache:     false,
 					},
 				},
@@ -197,8 +576,31 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Expect(dependencyService.ResolveCall.Receives.Version).To(Equal("*"))
 			Expect(dependencyService.ResolveCall.Receives.Stack).To(Equal("some-stack"))
 
+			Expect(layerValidator.ValidateCall.Receives.LayerPath).To(Equal(filepath.Join(layersDir, "yarn")))
+
 			Expect(dependencyService.InstallCall.CallCount).To(Equal(0))
 		})
+
+		context("when the layer contents are damaged", func() {
+			it.Before(func() {
+				layerValidator.ValidateCall.Returns.Error = errors.New("missing bin/yarn")
+			})
+
+			it("reinstalls yarn", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+					Stack:      "some-stack",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("dependency_sha", "some-sha"))
+
+				Expect(dependencyService.InstallCall.CallCount).To(Equal(1))
+
+				Expect(buffer.String()).To(ContainSubstring("Cached layer invalid: missing bin/yarn"))
+			})
+		})
 	})
 
 	context("failure cases", func() {
@@ -224,6 +626,25 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when SOURCE_DATE_EPOCH is malformed", func() {
+			it.Before(func() {
+				Expect(os.Setenv("SOURCE_DATE_EPOCH", "yesterday")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("SOURCE_DATE_EPOCH")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(`failed to parse SOURCE_DATE_EPOCH: "yesterday" is not a number of seconds`))
+			})
+		})
+
 		context("when the yarn dependency fails to resolve", func() {
 			it.Before(func() {
 				dependencyService.ResolveCall.Returns.Error = errors.New("failed to resolve yarn")
@@ -244,6 +665,137 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the yarn layer cannot be checksummed", func() {
+			it.Before(func() {
+				summer.SumCall.Stub = nil
+				summer.SumCall.Returns.Error = errors.New("failed to sum")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to checksum yarn layer: failed to sum"))
+			})
+		})
+
+		context("when the node version cannot be determined", func() {
+			it.Before(func() {
+				nodeExecutable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprintln(execution.Stderr, "node: command not found")
+					return errors.New("exit status 127")
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to determine node version: exit status 127: node: command not found"))
+			})
+		})
+
+		context("when the yarn.lock cannot be checksummed", func() {
+			it.Before(func() {
+				summer.SumCall.Stub = func(path string) (string, error) {
+					if filepath.Base(path) == "yarn.lock" {
+						return "", errors.New("failed to sum")
+					}
+
+					return "some-content-checksum", nil
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to checksum yarn.lock: failed to sum"))
+			})
+		})
+
+		context("when the buildpack.toml cannot be parsed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(cnbDir, "buildpack.toml"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse buildpack.toml:")))
+			})
+		})
+
+		context("when the yarn.lock cannot be parsed", func() {
+			it.Before(func() {
+				lockfileParser.ParseCall.Returns.Error = errors.New("failed to parse yarn.lock")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse yarn.lock"))
+			})
+		})
+
+		context("when the lockfile policy is violated", func() {
+			it.Before(func() {
+				policyEnforcer.EnforceCall.Returns.Error = errors.New("failed to enforce lockfile policy")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to enforce lockfile policy"))
+				Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the SBOM cannot be generated", func() {
+			it.Before(func() {
+				sbomGenerator.GenerateCall.Returns.Error = errors.New("failed to generate SBOM")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to generate SBOM"))
+			})
+		})
+
+		context("when the buildpack.yml cannot be parsed", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.Error = errors.New("failed to parse buildpack.yml")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					CNBPath:    cnbDir,
+					Layers:     packit.Layers{Path: layersDir},
+				})
+				Expect(err).To(MatchError("failed to parse buildpack.yml"))
+			})
+		})
+
 		context("when the yarn dependency fails to install", func() {
 			it.Before(func() {
 				dependencyService.InstallCall.Returns.Error = errors.New("failed to install yarn")
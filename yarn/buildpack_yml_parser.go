@@ -1,15 +1,62 @@
 package yarn
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"strings"
 
+	"github.com/Masterminds/semver"
 	yaml "gopkg.in/yaml.v2"
 )
 
+// Config is the schema of the yarn section of buildpack.yml. Unknown keys in
+// the section are rejected, so every supported option must be listed here.
 type Config struct {
-	Version string `yaml:"version"`
+	Version    string        `yaml:"version"`
+	Licenses   LicensePolicy `yaml:"licenses"`
+	Audit      AuditPolicy   `yaml:"audit"`
+	Registries []string      `yaml:"registries"`
 }
 
+// EnvironmentVariables returns the BP_YARN_* settings that replace the
+// options set in the config, in the form NAME="value". Environment variables
+// take precedence over buildpack.yml.
+func (c Config) EnvironmentVariables() []string {
+	settings := []struct {
+		name  string
+		value string
+	}{
+		{"BP_YARN_VERSION", c.Version},
+		{"BP_YARN_LICENSE_ALLOW", strings.Join(c.Licenses.Allow, ",")},
+		{"BP_YARN_LICENSE_DENY", strings.Join(c.Licenses.Deny, ",")},
+		{"BP_YARN_ADVISORY_DB", c.Audit.Advisories},
+		{"BP_YARN_AUDIT_THRESHOLD", c.Audit.Threshold},
+		{"BP_YARN_ALLOWED_REGISTRIES", strings.Join(c.Registries, ",")},
+	}
+
+	var variables []string
+	for _, setting := range settings {
+		if setting.value != "" {
+			variables = append(variables, fmt.Sprintf("%s=%q", setting.name, setting.value))
+		}
+	}
+
+	return variables
+}
+
+// configSections names the buildpack.yml section decoded into each type so
+// that unknown keys can be reported by their path in the file.
+var configSections = map[string]string{
+	"yarn.Config":        "yarn",
+	"yarn.LicensePolicy": "yarn.licenses",
+	"yarn.AuditPolicy":   "yarn.audit",
+}
+
+var unknownFieldError = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
 type BuildpackYMLParser struct{}
 
 func NewBuildpackYMLParser() BuildpackYMLParser {
@@ -17,21 +64,35 @@ func NewBuildpackYMLParser() BuildpackYMLParser {
 }
 
 func (p BuildpackYMLParser) Parse(path string) (Config, error) {
+	// buildpack.yml is shared with other buildpacks, so only the yarn section
+	// is decoded strictly and the other top-level keys are kept aside.
 	var buildpack struct {
-		Yarn Config `yaml:"yarn"`
+		Yarn   Config                 `yaml:"yarn"`
+		Others map[string]interface{} `yaml:",inline"`
 	}
 
-	file, err := os.Open(path)
-	if err != nil && !os.IsNotExist(err) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+
 		return Config{}, err
 	}
-	defer file.Close()
 
-	if !os.IsNotExist(err) {
-		err = yaml.NewDecoder(file).Decode(&buildpack)
-		if err != nil {
-			return Config{}, err
+	err = yaml.UnmarshalStrict(content, &buildpack)
+	if err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return Config{}, fmt.Errorf("invalid buildpack.yml:\n  %s", strings.Join(describeSchemaErrors(typeErr.Errors), "\n  "))
 		}
+
+		return Config{}, err
+	}
+
+	err = buildpack.Yarn.validate()
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid buildpack.yml: %w", err)
 	}
 
 	return buildpack.Yarn, nil
@@ -45,3 +106,43 @@ func (p BuildpackYMLParser) ParseVersion(path string) (string, error) {
 
 	return config.Version, nil
 }
+
+func (c Config) validate() error {
+	if c.Version != "" {
+		_, err := semver.NewConstraint(npmRange(c.Version))
+		if err != nil {
+			return fmt.Errorf("yarn.version %q is not a valid version constraint: %w", c.Version, err)
+		}
+	}
+
+	if c.Audit.Threshold != "" {
+		_, err := ParseSeverity(c.Audit.Threshold)
+		if err != nil {
+			return fmt.Errorf("yarn.audit.threshold: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// describeSchemaErrors rewrites the unknown field errors from the YAML decoder
+// to name the key by its path in buildpack.yml rather than by its Go type.
+func describeSchemaErrors(errs []string) []string {
+	var descriptions []string
+	for _, e := range errs {
+		matches := unknownFieldError.FindStringSubmatch(e)
+		if matches == nil {
+			descriptions = append(descriptions, e)
+			continue
+		}
+
+		section, ok := configSections[matches[3]]
+		if !ok {
+			section = matches[3]
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("line %s: unknown key %q in %s", matches[1], matches[2], section))
+	}
+
+	return descriptions
+}
@@ -44,6 +44,13 @@ yarn:
 			err := ioutil.WriteFile(path, []byte(`---
 yarn:
   version: "1.2.3"
+  licenses:
+    allow: ["MIT", "BSD-*"]
+    deny: ["GPL-*"]
+  audit:
+    advisories: advisories
+    threshold: high
+  registries: ["npm.example.com"]
 `), 0644)
 			Expect(err).NotTo(HaveOccurred())
 		})
@@ -52,8 +59,118 @@ yarn:
 			configData, err := parser.Parse(path)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(configData.Version).To(Equal("1.2.3"))
+			Expect(configData.Licenses).To(Equal(yarn.LicensePolicy{
+				Allow: []string{"MIT", "BSD-*"},
+				Deny:  []string{"GPL-*"},
+			}))
+			Expect(configData.Audit).To(Equal(yarn.AuditPolicy{
+				Advisories: "advisories",
+				Threshold:  "high",
+			}))
+			Expect(configData.Registries).To(Equal([]string{"npm.example.com"}))
 		})
 
+		context("when buildpack.yml configures other buildpacks", func() {
+			it.Before(func() {
+				err := ioutil.WriteFile(path, []byte(`---
+nodejs:
+  version: 12.x
+yarn:
+  version: 1.x
+`), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("ignores their sections", func() {
+				configData, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(configData.Version).To(Equal("1.x"))
+			})
+		})
+
+		context("when the version is an npm range with several comparators", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(path, []byte("yarn:\n  version: \">=1.21 <1.23\"\n"), 0644)).To(Succeed())
+			})
+
+			it("accepts it", func() {
+				configData, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(configData.Version).To(Equal(">=1.21 <1.23"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the yarn section has unknown keys", func() {
+				it.Before(func() {
+					err := ioutil.WriteFile(path, []byte(`---
+yarn:
+  verison: 1.22
+  audit:
+    treshold: high
+`), 0644)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("returns an error naming each key and its line", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(`invalid buildpack.yml:
+  line 3: unknown key "verison" in yarn
+  line 5: unknown key "treshold" in yarn.audit`))
+				})
+			})
+
+			context("when the version is not a valid constraint", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(path, []byte("yarn:\n  version: not-a-version\n"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring(`invalid buildpack.yml: yarn.version "not-a-version" is not a valid version constraint`)))
+				})
+			})
+
+			context("when the audit threshold is not a severity", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(path, []byte("yarn:\n  audit:\n    threshold: severe\n"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(`invalid buildpack.yml: yarn.audit.threshold: unknown severity "severe": must be one of low, moderate, high or critical`))
+				})
+			})
+		})
+	})
+
+	context("EnvironmentVariables", func() {
+		it("returns the environment variables replacing each option that is set", func() {
+			config := yarn.Config{
+				Version: "1.x",
+				Licenses: yarn.LicensePolicy{
+					Allow: []string{"MIT", "BSD-*"},
+				},
+				Audit: yarn.AuditPolicy{
+					Advisories: "advisories",
+					Threshold:  "high",
+				},
+				Registries: []string{"npm.example.com"},
+			}
+			Expect(config.EnvironmentVariables()).To(Equal([]string{
+				`BP_YARN_VERSION="1.x"`,
+				`BP_YARN_LICENSE_ALLOW="MIT,BSD-*"`,
+				`BP_YARN_ADVISORY_DB="advisories"`,
+				`BP_YARN_AUDIT_THRESHOLD="high"`,
+				`BP_YARN_ALLOWED_REGISTRIES="npm.example.com"`,
+			}))
+		})
+
+		context("when no options are set", func() {
+			it("returns nothing", func() {
+				Expect(yarn.Config{}.EnvironmentVariables()).To(BeEmpty())
+			})
+		})
 	})
 
 	context("ParseVersion", func() {
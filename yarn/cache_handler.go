@@ -1,12 +1,95 @@
 package yarn
 
+import (
+	"fmt"
+	"strconv"
+)
+
+type CacheMismatchReason string
+
+const (
+	CacheMismatchNoLayer                 CacheMismatchReason = "no cached layer"
+	CacheMismatchSchemaVersionChanged    CacheMismatchReason = "metadata schema version changed"
+	CacheMismatchMissingKey              CacheMismatchReason = "metadata key missing"
+	CacheMismatchSHAChanged              CacheMismatchReason = "SHA changed"
+	CacheMismatchBuildpackVersionChanged CacheMismatchReason = "buildpack version changed"
+	CacheMismatchStackChanged            CacheMismatchReason = "stack changed"
+)
+
+// CacheKey holds the values a layer must have been built with for it to be
+// reused.
+type CacheKey struct {
+	SHA              string
+	BuildpackVersion string
+	Stack            string
+}
+
+// CacheMismatch describes why a layer could not be reused. The zero value
+// means the layer matched.
+type CacheMismatch struct {
+	Reason CacheMismatchReason
+	Key    string
+	Old    string
+	New    string
+}
+
+func (m CacheMismatch) String() string {
+	if m.Reason == CacheMismatchNoLayer {
+		return string(m.Reason)
+	}
+
+	if m.Reason == CacheMismatchMissingKey {
+		return fmt.Sprintf("%s: %s", m.Reason, m.Key)
+	}
+
+	return fmt.Sprintf("%s (%s -> %s)", m.Reason, m.Old, m.New)
+}
+
 type CacheHandler struct{}
 
 func NewCacheHandler() CacheHandler {
 	return CacheHandler{}
 }
 
-func (ch CacheHandler) Match(metadata map[string]interface{}, key, sha string) bool {
-	value, ok := metadata[key].(string)
-	return value == sha && ok
+func (ch CacheHandler) Match(metadata LayerMetadata, key CacheKey) (bool, CacheMismatch) {
+	if metadata.empty() {
+		return false, CacheMismatch{Reason: CacheMismatchNoLayer}
+	}
+
+	if metadata.SchemaVersion != LayerMetadataSchemaVersion {
+		return false, CacheMismatch{
+			Reason: CacheMismatchSchemaVersionChanged,
+			Key:    metadataKeySchemaVersion,
+			Old:    strconv.Itoa(metadata.SchemaVersion),
+			New:    strconv.Itoa(LayerMetadataSchemaVersion),
+		}
+	}
+
+	checks := []struct {
+		key    string
+		old    string
+		new    string
+		reason CacheMismatchReason
+	}{
+		{metadataKeyDependencySHA, metadata.DependencySHA, key.SHA, CacheMismatchSHAChanged},
+		{metadataKeyBuildpackVersion, metadata.BuildpackVersion, key.BuildpackVersion, CacheMismatchBuildpackVersionChanged},
+		{metadataKeyStack, metadata.Stack, key.Stack, CacheMismatchStackChanged},
+	}
+
+	for _, check := range checks {
+		if check.old == "" {
+			return false, CacheMismatch{Reason: CacheMismatchMissingKey, Key: check.key}
+		}
+
+		if check.old != check.new {
+			return false, CacheMismatch{
+				Reason: check.reason,
+				Key:    check.key,
+				Old:    check.old,
+				New:    check.new,
+			}
+		}
+	}
+
+	return true, CacheMismatch{}
 }
@@ -21,45 +21,128 @@ func testCacheHandler(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("Match", func() {
-		var metadata map[string]interface{}
+		var (
+			metadata yarn.LayerMetadata
+			key      yarn.CacheKey
+		)
 
 		it.Before(func() {
-			metadata = map[string]interface{}{
-				"cache-sha": "some-sha",
+			metadata = yarn.LayerMetadata{
+				SchemaVersion:    2,
+				DependencySHA:    "some-sha",
+				BuildpackVersion: "some-buildpack-version",
+				Stack:            "some-stack",
+			}
+
+			key = yarn.CacheKey{
+				SHA:              "some-sha",
+				BuildpackVersion: "some-buildpack-version",
+				Stack:            "some-stack",
 			}
 		})
 
-		context("when the layer metadata and choosen dependency shas match", func() {
-			it("it returns true and no error", func() {
-				match := cacheHandler.Match(metadata, "cache-sha", "some-sha")
+		context("when the layer metadata matches the cache key", func() {
+			it("returns true and an empty mismatch", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
 				Expect(match).To(BeTrue())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{}))
+			})
+		})
+
+		context("when the layer has never been built", func() {
+			it.Before(func() {
+				metadata = yarn.ParseLayerMetadata(nil)
+			})
+
+			it("returns false and a no cached layer mismatch", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
+				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{Reason: yarn.CacheMismatchNoLayer}))
+				Expect(mismatch.String()).To(Equal("no cached layer"))
 			})
 		})
 
 		context("when the layer metadata and choosen dependency shas do not match", func() {
-			it("it returns false and no error", func() {
-				match := cacheHandler.Match(metadata, "cache-sha", "other-sha")
+			it.Before(func() {
+				key.SHA = "other-sha"
+			})
+
+			it("returns false and the old and new shas", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
+				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{
+					Reason: yarn.CacheMismatchSHAChanged,
+					Key:    "dependency_sha",
+					Old:    "some-sha",
+					New:    "other-sha",
+				}))
+				Expect(mismatch.String()).To(Equal("SHA changed (some-sha -> other-sha)"))
+			})
+		})
+
+		context("when the buildpack version has changed", func() {
+			it.Before(func() {
+				key.BuildpackVersion = "other-buildpack-version"
+			})
+
+			it("returns false and the old and new buildpack versions", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
+				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{
+					Reason: yarn.CacheMismatchBuildpackVersionChanged,
+					Key:    "buildpack_version",
+					Old:    "some-buildpack-version",
+					New:    "other-buildpack-version",
+				}))
+			})
+		})
+
+		context("when the stack has changed", func() {
+			it.Before(func() {
+				key.Stack = "other-stack"
+			})
+
+			it("returns false and the old and new stacks", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
 				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{
+					Reason: yarn.CacheMismatchStackChanged,
+					Key:    "stack",
+					Old:    "some-stack",
+					New:    "other-stack",
+				}))
 			})
 		})
 
 		context("when the layer metadata does not contain the dependency-sha", func() {
-			it("it returns false and no error", func() {
-				match := cacheHandler.Match(map[string]interface{}{}, "cache-sha", "some-sha")
+			it("returns false and the missing key", func() {
+				metadata.DependencySHA = ""
+
+				match, mismatch := cacheHandler.Match(metadata, key)
 				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{
+					Reason: yarn.CacheMismatchMissingKey,
+					Key:    "dependency_sha",
+				}))
+				Expect(mismatch.String()).To(Equal("metadata key missing: dependency_sha"))
 			})
 		})
 
-		context("when the cache key has a type mismatch", func() {
+		context("when the metadata was written with a different schema version", func() {
 			it.Before(func() {
-				metadata["cache-sha"] = 10
+				metadata.SchemaVersion = 3
 			})
 
-			it("returns false", func() {
-				match := cacheHandler.Match(metadata, "cache-sha", "some-sha")
+			it("returns false and the old and new schema versions", func() {
+				match, mismatch := cacheHandler.Match(metadata, key)
 				Expect(match).To(BeFalse())
+				Expect(mismatch).To(Equal(yarn.CacheMismatch{
+					Reason: yarn.CacheMismatchSchemaVersionChanged,
+					Key:    "schema_version",
+					Old:    "3",
+					New:    "2",
+				}))
 			})
 		})
 	})
-
 }
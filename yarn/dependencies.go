@@ -0,0 +1,45 @@
+package yarn
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver"
+	"github.com/cloudfoundry/packit/postal"
+)
+
+// listDependencies returns every dependency in buildpack.toml with the given
+// id that supports the stack, newest version first. These are the candidates
+// the dependency service chooses from when resolving a version.
+func listDependencies(path, id, stack string) ([]postal.Dependency, error) {
+	var buildpack struct {
+		Metadata struct {
+			Dependencies []postal.Dependency `toml:"dependencies"`
+		} `toml:"metadata"`
+	}
+
+	_, err := toml.DecodeFile(path, &buildpack)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse buildpack.toml: %w", err)
+	}
+
+	var dependencies []postal.Dependency
+	for _, dependency := range buildpack.Metadata.Dependencies {
+		if dependency.ID == id && dependency.Stacks.Include(stack) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+
+	sort.SliceStable(dependencies, func(i, j int) bool {
+		iVersion, iErr := semver.NewVersion(dependencies[i].Version)
+		jVersion, jErr := semver.NewVersion(dependencies[j].Version)
+		if iErr != nil || jErr != nil {
+			return dependencies[i].Version > dependencies[j].Version
+		}
+
+		return iVersion.GreaterThan(jVersion)
+	})
+
+	return dependencies, nil
+}
@@ -0,0 +1,102 @@
+package yarn
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	scopeProduction  = "production"
+	scopeDevelopment = "development"
+)
+
+type graphPackage struct {
+	entry        LockfileEntry
+	purl         string
+	ref          string
+	scope        string
+	dependencies []string
+}
+
+// resolveDependencyGraph links the lockfile entries into a dependency graph and
+// marks every package reachable from the package.json dependencies as a
+// production package. Packages only reachable from devDependencies are
+// marked as development packages.
+//
+// Every package has a unique ref. Berry lists patched packages, such as the
+// builtin typescript patch, as separate entries with the same name and
+// version as the package they patch, so repeated package URLs are numbered.
+func resolveDependencyGraph(pkg PackageJSON, entries []LockfileEntry) []*graphPackage {
+	var packages []*graphPackage
+	descriptors := map[string]*graphPackage{}
+	occurrences := map[string]int{}
+	for _, entry := range entries {
+		p := &graphPackage{
+			entry: entry,
+			purl:  packageURL(entry.Name, entry.Version),
+		}
+
+		occurrences[p.purl]++
+		p.ref = p.purl
+		if occurrences[p.purl] > 1 {
+			p.ref = fmt.Sprintf("%s#%d", p.purl, occurrences[p.purl])
+		}
+
+		for _, descriptor := range entry.Descriptors {
+			descriptors[descriptor] = p
+		}
+
+		packages = append(packages, p)
+	}
+
+	// Berry writes descriptors with an explicit protocol, while dependency
+	// ranges in the lockfile and package.json default to the npm protocol.
+	lookup := func(name, rng string) (*graphPackage, bool) {
+		if p, ok := descriptors[name+"@"+rng]; ok {
+			return p, true
+		}
+
+		p, ok := descriptors[name+"@npm:"+rng]
+		return p, ok
+	}
+
+	for _, p := range packages {
+		for name, rng := range p.entry.Dependencies {
+			if dependency, ok := lookup(name, rng); ok {
+				p.dependencies = append(p.dependencies, dependency.ref)
+			}
+		}
+		sort.Strings(p.dependencies)
+	}
+
+	var mark func(name, rng, scope string)
+	mark = func(name, rng, scope string) {
+		p, ok := lookup(name, rng)
+		if !ok || p.scope == scope || p.scope == scopeProduction {
+			return
+		}
+
+		p.scope = scope
+		for name, rng := range p.entry.Dependencies {
+			mark(name, rng, scope)
+		}
+	}
+
+	for _, dependencies := range []map[string]string{pkg.Dependencies, pkg.OptionalDependencies} {
+		for name, rng := range dependencies {
+			mark(name, rng, scopeProduction)
+		}
+	}
+
+	for name, rng := range pkg.DevDependencies {
+		mark(name, rng, scopeDevelopment)
+	}
+
+	for _, p := range packages {
+		if p.scope == "" {
+			p.scope = scopeProduction
+		}
+	}
+
+	return packages
+}
@@ -2,9 +2,12 @@ package yarn
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/Masterminds/semver"
 	"github.com/cloudfoundry/packit"
 )
 
@@ -29,7 +32,16 @@ type BuildpackYMLVersionParser interface {
 	ParseVersion(path string) (version string, err error)
 }
 
-func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser BuildpackYMLVersionParser) packit.DetectFunc {
+//go:generate faux --interface VersionFileParser --output fakes/version_file_parser.go
+type VersionFileParser interface {
+	ParseVersion(path string) (version string, err error)
+}
+
+// nodeVersionFiles are the files version managers such as nvm read the node
+// version of an application from.
+var nodeVersionFiles = []string{".nvmrc", ".node-version"}
+
+func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser BuildpackYMLVersionParser, versionFileParser VersionFileParser) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
 		var requires []packit.BuildPlanRequirement
 
@@ -38,10 +50,26 @@ func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser Build
 			return packit.DetectResult{}, err
 		}
 
+		yarnVersionSource := "buildpack.yml"
+		yarnVersionField := "buildpack.yml yarn.version"
+		if version, ok := os.LookupEnv("BP_YARN_VERSION"); ok {
+			yarnVersion = version
+			yarnVersionSource = "BP_YARN_VERSION"
+			yarnVersionField = "BP_YARN_VERSION"
+		}
+
+		yarnVersion, err = versionConstraint(yarnVersion, yarnVersionField)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
 		if yarnVersion != "" {
 			requires = append(requires, packit.BuildPlanRequirement{
 				Name:    PlanDependencyYarn,
 				Version: yarnVersion,
+				Metadata: BuildPlanMetadata{
+					VersionSource: yarnVersionSource,
+				},
 			})
 		}
 
@@ -54,6 +82,11 @@ func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser Build
 			return packit.DetectResult{}, err
 		}
 
+		nodeVersion, err = versionConstraint(nodeVersion, "package.json engines.node")
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
 		nodeRequirement := packit.BuildPlanRequirement{
 			Name: PlanDependencyNode,
 			Metadata: BuildPlanMetadata{
@@ -73,6 +106,30 @@ func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser Build
 
 		requires = append(requires, nodeRequirement)
 
+		for _, name := range nodeVersionFiles {
+			version, err := versionFileParser.ParseVersion(filepath.Join(context.WorkingDir, name))
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			version, err = versionConstraint(version, name)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if version != "" {
+				requires = append(requires, packit.BuildPlanRequirement{
+					Name:    PlanDependencyNode,
+					Version: version,
+					Metadata: BuildPlanMetadata{
+						VersionSource: name,
+						Build:         true,
+						Launch:        true,
+					},
+				})
+			}
+		}
+
 		return packit.DetectResult{
 			Plan: packit.BuildPlan{
 				Provides: []packit.BuildPlanProvision{
@@ -83,3 +140,49 @@ func Detect(packageJSONParser PackageJSONVersionParser, buildpackYMLParser Build
 		}, nil
 	}
 }
+
+// versionConstraint returns the version as a constraint other buildpacks can
+// resolve, rejecting versions they would fail to resolve and naming the file
+// and field they came from. Empty versions are valid and mean any version.
+func versionConstraint(version, source string) (string, error) {
+	if version == "" {
+		return "", nil
+	}
+
+	constraint := npmRange(version)
+
+	_, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q in %s: %w", version, source, err)
+	}
+
+	return constraint, nil
+}
+
+// npmRange rewrites the whitespace separated comparators npm accepts, as in
+// ">=12 <15", into the comma separated form understood by semver. Hyphen
+// ranges and || alternatives are kept as they are.
+func npmRange(version string) string {
+	var sets []string
+	for _, set := range strings.Split(version, "||") {
+		fields := strings.Fields(strings.Replace(set, ",", " ", -1))
+
+		var comparators []string
+		for i := 0; i < len(fields); i++ {
+			switch {
+			case fields[i] == "-" && len(comparators) > 0 && i+1 < len(fields):
+				comparators[len(comparators)-1] += " - " + fields[i+1]
+				i++
+			case strings.Trim(fields[i], "<>=!~^") == "" && i+1 < len(fields):
+				comparators = append(comparators, fields[i]+fields[i+1])
+				i++
+			default:
+				comparators = append(comparators, fields[i])
+			}
+		}
+
+		sets = append(sets, strings.Join(comparators, ", "))
+	}
+
+	return strings.Join(sets, " || ")
+}
@@ -21,6 +21,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		packageJSONParser  *fakes.PackageJSONVersionParser
 		buildpackYMLParser *fakes.BuildpackYMLVersionParser
+		versionFileParser  *fakes.VersionFileParser
 		workingDir         string
 		detect             packit.DetectFunc
 	)
@@ -34,11 +35,13 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect(err).NotTo(HaveOccurred())
 
 		packageJSONParser = &fakes.PackageJSONVersionParser{}
-		packageJSONParser.ParseVersionCall.Returns.Version = "some-version"
+		packageJSONParser.ParseVersionCall.Returns.Version = "12.x"
 
 		buildpackYMLParser = &fakes.BuildpackYMLVersionParser{}
 
-		detect = yarn.Detect(packageJSONParser, buildpackYMLParser)
+		versionFileParser = &fakes.VersionFileParser{}
+
+		detect = yarn.Detect(packageJSONParser, buildpackYMLParser, versionFileParser)
 	})
 
 	it("returns a plan that provides yarn", func() {
@@ -53,7 +56,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			Requires: []packit.BuildPlanRequirement{
 				{
 					Name:    "node",
-					Version: "some-version",
+					Version: "12.x",
 					Metadata: yarn.BuildPlanMetadata{
 						VersionSource: "package.json",
 						Build:         true,
@@ -67,6 +70,107 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect(buildpackYMLParser.ParseVersionCall.Receives.Path).To(Equal(filepath.Join(workingDir, "buildpack.yml")))
 	})
 
+	context("when the node version is pinned in .nvmrc and .node-version", func() {
+		it.Before(func() {
+			versionFileParser.ParseVersionCall.Stub = func(path string) (string, error) {
+				switch filepath.Base(path) {
+				case ".nvmrc":
+					return "12.*", nil
+				case ".node-version":
+					return "12.16.1", nil
+				}
+
+				return "", nil
+			}
+		})
+
+		it("requires node with each version and its source", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name:    "node",
+					Version: "12.x",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: "package.json",
+						Build:         true,
+						Launch:        true,
+					},
+				},
+				{
+					Name:    "node",
+					Version: "12.*",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: ".nvmrc",
+						Build:         true,
+						Launch:        true,
+					},
+				},
+				{
+					Name:    "node",
+					Version: "12.16.1",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: ".node-version",
+						Build:         true,
+						Launch:        true,
+					},
+				},
+			}))
+
+			Expect(versionFileParser.ParseVersionCall.CallCount).To(Equal(2))
+		})
+	})
+
+	context("when the versions are npm ranges with several comparators", func() {
+		it.Before(func() {
+			packageJSONParser.ParseVersionCall.Returns.Version = ">=10.0.0 <13.0.0"
+			buildpackYMLParser.ParseVersionCall.Returns.Version = ">= 1.21 < 1.23"
+			versionFileParser.ParseVersionCall.Stub = func(path string) (string, error) {
+				if filepath.Base(path) == ".nvmrc" {
+					return ">=12 <15", nil
+				}
+
+				return "", nil
+			}
+		})
+
+		it("requires the ranges as comma separated constraints", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(Equal([]packit.BuildPlanRequirement{
+				{
+					Name:    "yarn",
+					Version: ">=1.21, <1.23",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: "buildpack.yml",
+					},
+				},
+				{
+					Name:    "node",
+					Version: ">=10.0.0, <13.0.0",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: "package.json",
+						Build:         true,
+						Launch:        true,
+					},
+				},
+				{
+					Name:    "node",
+					Version: ">=12, <15",
+					Metadata: yarn.BuildPlanMetadata{
+						VersionSource: ".nvmrc",
+						Build:         true,
+						Launch:        true,
+					},
+				},
+			}))
+		})
+	})
+
 	context("when the node version is not in the package.json file", func() {
 		it.Before(func() {
 			packageJSONParser.ParseVersionCall.Returns.Version = ""
@@ -97,9 +201,34 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when BP_YARN_VERSION is set", func() {
+		it.Before(func() {
+			buildpackYMLParser.ParseVersionCall.Returns.Version = "1.21.x"
+			Expect(os.Setenv("BP_YARN_VERSION", "1.22.x")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_YARN_VERSION")).To(Succeed())
+		})
+
+		it("requires the version from the environment over buildpack.yml", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(ContainElement(packit.BuildPlanRequirement{
+				Name:    "yarn",
+				Version: "1.22.x",
+				Metadata: yarn.BuildPlanMetadata{
+					VersionSource: "BP_YARN_VERSION",
+				},
+			}))
+		})
+	})
+
 	context("there is a yarn version in the buildpack.yml", func() {
 		it.Before(func() {
-			buildpackYMLParser.ParseVersionCall.Returns.Version = "some-version"
+			buildpackYMLParser.ParseVersionCall.Returns.Version = "1.21.x"
 		})
 
 		it("returns a plan that provides and requires yarn", func() {
@@ -114,10 +243,13 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Requires: []packit.BuildPlanRequirement{
 					{
 						Name:    "yarn",
-						Version: "some-version",
+						Version: "1.21.x",
+						Metadata: yarn.BuildPlanMetadata{
+							VersionSource: "buildpack.yml",
+						},
 					}, {
 						Name:    "node",
-						Version: "some-version",
+						Version: "12.x",
 						Metadata: yarn.BuildPlanMetadata{
 							VersionSource: "package.json",
 							Build:         true,
@@ -172,5 +304,74 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).To(MatchError("failed to read package.json"))
 			})
 		})
+
+		context("when engines.node is not a valid constraint", func() {
+			it.Before(func() {
+				packageJSONParser.ParseVersionCall.Returns.Version = ">=10 <"
+			})
+
+			it("returns an error naming the file and field", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid version constraint ">=10 <" in package.json engines.node:`)))
+			})
+		})
+
+		context("when the buildpack.yml yarn version is not a valid constraint", func() {
+			it.Before(func() {
+				buildpackYMLParser.ParseVersionCall.Returns.Version = "latest"
+			})
+
+			it("returns an error naming the file and field", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid version constraint "latest" in buildpack.yml yarn.version:`)))
+			})
+		})
+
+		context("when BP_YARN_VERSION is not a valid constraint", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_VERSION", "latest")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_YARN_VERSION")).To(Succeed())
+			})
+
+			it("returns an error naming the variable", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid version constraint "latest" in BP_YARN_VERSION:`)))
+			})
+		})
+
+		context("when a node version file is not a valid constraint", func() {
+			it.Before(func() {
+				versionFileParser.ParseVersionCall.Returns.Version = "not-a-version"
+			})
+
+			it("returns an error naming the file", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid version constraint "not-a-version" in .nvmrc:`)))
+			})
+		})
+
+		context("when a node version file cannot be read", func() {
+			it.Before(func() {
+				versionFileParser.ParseVersionCall.Returns.Err = errors.New("failed to read .nvmrc")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to read .nvmrc"))
+			})
+		})
 	})
 }
@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type BuildpackConfigParser struct {
+	ParseCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Config yarn.Config
+			Error  error
+		}
+		Stub func(string) (yarn.Config, error)
+	}
+}
+
+func (f *BuildpackConfigParser) Parse(param1 string) (yarn.Config, error) {
+	f.ParseCall.Lock()
+	defer f.ParseCall.Unlock()
+	f.ParseCall.CallCount++
+	f.ParseCall.Receives.Path = param1
+	if f.ParseCall.Stub != nil {
+		return f.ParseCall.Stub(param1)
+	}
+	return f.ParseCall.Returns.Config, f.ParseCall.Returns.Error
+}
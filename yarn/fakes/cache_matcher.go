@@ -1,35 +1,35 @@
 package fakes
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
 
 type CacheMatcher struct {
 	MatchCall struct {
 		sync.Mutex
 		CallCount int
 		Receives  struct {
-			Metadata map[string]interface {
-			}
-			Key string
-			Sha string
+			Metadata yarn.LayerMetadata
+			Key      yarn.CacheKey
 		}
 		Returns struct {
-			Bool bool
+			Bool          bool
+			CacheMismatch yarn.CacheMismatch
 		}
-		Stub func(map[string]interface {
-		}, string, string) bool
+		Stub func(yarn.LayerMetadata, yarn.CacheKey) (bool, yarn.CacheMismatch)
 	}
 }
 
-func (f *CacheMatcher) Match(param1 map[string]interface {
-}, param2 string, param3 string) bool {
+func (f *CacheMatcher) Match(param1 yarn.LayerMetadata, param2 yarn.CacheKey) (bool, yarn.CacheMismatch) {
 	f.MatchCall.Lock()
 	defer f.MatchCall.Unlock()
 	f.MatchCall.CallCount++
 	f.MatchCall.Receives.Metadata = param1
 	f.MatchCall.Receives.Key = param2
-	f.MatchCall.Receives.Sha = param3
 	if f.MatchCall.Stub != nil {
-		return f.MatchCall.Stub(param1, param2, param3)
+		return f.MatchCall.Stub(param1, param2)
 	}
-	return f.MatchCall.Returns.Bool
+	return f.MatchCall.Returns.Bool, f.MatchCall.Returns.CacheMismatch
 }
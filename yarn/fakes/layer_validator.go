@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type LayerValidator struct {
+	ValidateCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			LayerPath string
+			Metadata  yarn.LayerMetadata
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, yarn.LayerMetadata) error
+	}
+}
+
+func (f *LayerValidator) Validate(param1 string, param2 yarn.LayerMetadata) error {
+	f.ValidateCall.Lock()
+	defer f.ValidateCall.Unlock()
+	f.ValidateCall.CallCount++
+	f.ValidateCall.Receives.LayerPath = param1
+	f.ValidateCall.Receives.Metadata = param2
+	if f.ValidateCall.Stub != nil {
+		return f.ValidateCall.Stub(param1, param2)
+	}
+	return f.ValidateCall.Returns.Error
+}
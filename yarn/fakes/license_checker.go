@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type LicenseChecker struct {
+	ScanCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Entries    []yarn.LockfileEntry
+			Policy     yarn.LicensePolicy
+		}
+		Returns struct {
+			LicenseReport yarn.LicenseReport
+			Error         error
+		}
+		Stub func(string, []yarn.LockfileEntry, yarn.LicensePolicy) (yarn.LicenseReport, error)
+	}
+}
+
+func (f *LicenseChecker) Scan(param1 string, param2 []yarn.LockfileEntry, param3 yarn.LicensePolicy) (yarn.LicenseReport, error) {
+	f.ScanCall.Lock()
+	defer f.ScanCall.Unlock()
+	f.ScanCall.CallCount++
+	f.ScanCall.Receives.WorkingDir = param1
+	f.ScanCall.Receives.Entries = param2
+	f.ScanCall.Receives.Policy = param3
+	if f.ScanCall.Stub != nil {
+		return f.ScanCall.Stub(param1, param2, param3)
+	}
+	return f.ScanCall.Returns.LicenseReport, f.ScanCall.Returns.Error
+}
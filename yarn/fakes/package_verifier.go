@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type PackageVerifier struct {
+	VerifyCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Entries    []yarn.LockfileEntry
+		}
+		Returns struct {
+			IntegrityMismatchSlice []yarn.IntegrityMismatch
+			Error                  error
+		}
+		Stub func(string, []yarn.LockfileEntry) ([]yarn.IntegrityMismatch, error)
+	}
+}
+
+func (f *PackageVerifier) Verify(param1 string, param2 []yarn.LockfileEntry) ([]yarn.IntegrityMismatch, error) {
+	f.VerifyCall.Lock()
+	defer f.VerifyCall.Unlock()
+	f.VerifyCall.CallCount++
+	f.VerifyCall.Receives.WorkingDir = param1
+	f.VerifyCall.Receives.Entries = param2
+	if f.VerifyCall.Stub != nil {
+		return f.VerifyCall.Stub(param1, param2)
+	}
+	return f.VerifyCall.Returns.IntegrityMismatchSlice, f.VerifyCall.Returns.Error
+}
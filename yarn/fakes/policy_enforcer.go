@@ -0,0 +1,36 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type PolicyEnforcer struct {
+	EnforceCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Entries    []yarn.LockfileEntry
+			Config     yarn.Config
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, []yarn.LockfileEntry, yarn.Config) error
+	}
+}
+
+func (f *PolicyEnforcer) Enforce(param1 string, param2 []yarn.LockfileEntry, param3 yarn.Config) error {
+	f.EnforceCall.Lock()
+	defer f.EnforceCall.Unlock()
+	f.EnforceCall.CallCount++
+	f.EnforceCall.Receives.WorkingDir = param1
+	f.EnforceCall.Receives.Entries = param2
+	f.EnforceCall.Receives.Config = param3
+	if f.EnforceCall.Stub != nil {
+		return f.EnforceCall.Stub(param1, param2, param3)
+	}
+	return f.EnforceCall.Returns.Error
+}
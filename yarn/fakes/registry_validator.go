@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type RegistryValidator struct {
+	CheckCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Entries    []yarn.LockfileEntry
+			Allowed    []string
+		}
+		Returns struct {
+			RegistryViolationSlice []yarn.RegistryViolation
+			Error                  error
+		}
+		Stub func(string, []yarn.LockfileEntry, []string) ([]yarn.RegistryViolation, error)
+	}
+}
+
+func (f *RegistryValidator) Check(param1 string, param2 []yarn.LockfileEntry, param3 []string) ([]yarn.RegistryViolation, error) {
+	f.CheckCall.Lock()
+	defer f.CheckCall.Unlock()
+	f.CheckCall.CallCount++
+	f.CheckCall.Receives.WorkingDir = param1
+	f.CheckCall.Receives.Entries = param2
+	f.CheckCall.Receives.Allowed = param3
+	if f.CheckCall.Stub != nil {
+		return f.CheckCall.Stub(param1, param2, param3)
+	}
+	return f.CheckCall.Returns.RegistryViolationSlice, f.CheckCall.Returns.Error
+}
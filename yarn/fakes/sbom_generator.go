@@ -0,0 +1,42 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/cloudfoundry/packit"
+)
+
+type SBOMGenerator struct {
+	GenerateCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			LayerPath  string
+			Entries    []yarn.LockfileEntry
+			Info       packit.BuildpackInfo
+			Created    time.Time
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, []yarn.LockfileEntry, packit.BuildpackInfo, time.Time) error
+	}
+}
+
+func (f *SBOMGenerator) Generate(param1 string, param2 string, param3 []yarn.LockfileEntry, param4 packit.BuildpackInfo, param5 time.Time) error {
+	f.GenerateCall.Lock()
+	defer f.GenerateCall.Unlock()
+	f.GenerateCall.CallCount++
+	f.GenerateCall.Receives.WorkingDir = param1
+	f.GenerateCall.Receives.LayerPath = param2
+	f.GenerateCall.Receives.Entries = param3
+	f.GenerateCall.Receives.Info = param4
+	f.GenerateCall.Receives.Created = param5
+	if f.GenerateCall.Stub != nil {
+		return f.GenerateCall.Stub(param1, param2, param3, param4, param5)
+	}
+	return f.GenerateCall.Returns.Error
+}
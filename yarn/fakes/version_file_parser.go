@@ -0,0 +1,29 @@
+package fakes
+
+import "sync"
+
+type VersionFileParser struct {
+	ParseVersionCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Version string
+			Err     error
+		}
+		Stub func(string) (string, error)
+	}
+}
+
+func (f *VersionFileParser) ParseVersion(param1 string) (string, error) {
+	f.ParseVersionCall.Lock()
+	defer f.ParseVersionCall.Unlock()
+	f.ParseVersionCall.CallCount++
+	f.ParseVersionCall.Receives.Path = param1
+	if f.ParseVersionCall.Stub != nil {
+		return f.ParseVersionCall.Stub(param1)
+	}
+	return f.ParseVersionCall.Returns.Version, f.ParseVersionCall.Returns.Err
+}
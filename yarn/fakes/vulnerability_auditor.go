@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type VulnerabilityAuditor struct {
+	AuditCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			DatabasePath string
+			Entries      []yarn.LockfileEntry
+		}
+		Returns struct {
+			AuditReport yarn.AuditReport
+			Error       error
+		}
+		Stub func(string, []yarn.LockfileEntry) (yarn.AuditReport, error)
+	}
+}
+
+func (f *VulnerabilityAuditor) Audit(param1 string, param2 []yarn.LockfileEntry) (yarn.AuditReport, error) {
+	f.AuditCall.Lock()
+	defer f.AuditCall.Unlock()
+	f.AuditCall.CallCount++
+	f.AuditCall.Receives.DatabasePath = param1
+	f.AuditCall.Receives.Entries = param2
+	if f.AuditCall.Stub != nil {
+		return f.AuditCall.Stub(param1, param2)
+	}
+	return f.AuditCall.Returns.AuditReport, f.AuditCall.Returns.Error
+}
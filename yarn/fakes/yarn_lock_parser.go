@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+)
+
+type YarnLockParser struct {
+	ParseCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			LockfileEntrySlice []yarn.LockfileEntry
+			Error              error
+		}
+		Stub func(string) ([]yarn.LockfileEntry, error)
+	}
+}
+
+func (f *YarnLockParser) Parse(param1 string) ([]yarn.LockfileEntry, error) {
+	f.ParseCall.Lock()
+	defer f.ParseCall.Unlock()
+	f.ParseCall.CallCount++
+	f.ParseCall.Receives.Path = param1
+	if f.ParseCall.Stub != nil {
+		return f.ParseCall.Stub(param1)
+	}
+	return f.ParseCall.Returns.LockfileEntrySlice, f.ParseCall.Returns.Error
+}
@@ -9,13 +9,26 @@ import (
 
 func TestUnitYarn(t *testing.T) {
 	suite := spec.New("yarn", spec.Report(report.Terminal{}))
+	suite("AdvisoryAuditor", testAdvisoryAuditor)
 	suite("Build", testBuild)
+	suite("BuildReport", testBuildReport)
 	suite("CacheHandler", testCacheHandler)
 	suite("Clock", testClock)
 	suite("BuildpackYAMLParser", testBuildpackYMLParser)
 	suite("Detect", testDetect)
 	// suite("InstallProcess", testInstallProcess)
+	suite("IntegrityChecker", testIntegrityChecker)
+	suite("LicenseScanner", testLicenseScanner)
+	suite("LayerMetadata", testLayerMetadata)
+	suite("LockfileParser", testLockfileParser)
+	suite("LockfilePolicy", testLockfilePolicy)
 	suite("LogEmitter", testLogEmitter)
+	suite("MirrorVerifier", testMirrorVerifier)
+	suite("NodeVersionFileParser", testNodeVersionFileParser)
 	suite("PackageJSONParser", testPackageJSONParser)
+	suite("RedactingWriter", testRedactingWriter)
+	suite("RegistryChecker", testRegistryChecker)
+	suite("SBOMWriter", testSBOMWriter)
+	suite("YarnrcParser", testYarnrcParser)
 	suite.Run(t)
 }
@@ -0,0 +1,51 @@
+package yarn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type IntegrityChecker struct {
+	summer Summer
+}
+
+func NewIntegrityChecker(summer Summer) IntegrityChecker {
+	return IntegrityChecker{
+		summer: summer,
+	}
+}
+
+// Validate checks that a yarn layer whose metadata matched still contains a
+// usable yarn installation. The layer is cached, so the lifecycle restores its
+// contents along with its metadata and a missing bin/yarn means the restored
+// layer is broken.
+func (c IntegrityChecker) Validate(layerPath string, metadata LayerMetadata) error {
+	executable := filepath.Join(layerPath, "bin", "yarn")
+	info, err := os.Stat(executable)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("missing bin/yarn")
+		}
+
+		return err
+	}
+
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return errors.New("bin/yarn is not executable")
+	}
+
+	if metadata.ContentChecksum != "" {
+		checksum, err := c.summer.Sum(filepath.Join(layerPath, "bin"))
+		if err != nil {
+			return err
+		}
+
+		if checksum != metadata.ContentChecksum {
+			return fmt.Errorf("content checksum changed (%s -> %s)", metadata.ContentChecksum, checksum)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package yarn_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/ForestEckhardt/yarn-cnb/yarn/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testIntegrityChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerPath string
+		summer    *fakes.Summer
+		checker   yarn.IntegrityChecker
+	)
+
+	it.Before(func() {
+		var err error
+		layerPath, err = ioutil.TempDir("", "yarn-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(layerPath, "bin"), os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(layerPath, "bin", "yarn"), nil, 0755)).To(Succeed())
+
+		summer = &fakes.Summer{}
+		summer.SumCall.Returns.String = "some-content-checksum"
+
+		checker = yarn.NewIntegrityChecker(summer)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layerPath)).To(Succeed())
+	})
+
+	context("Validate", func() {
+		it("accepts a layer with an executable bin/yarn", func() {
+			err := checker.Validate(layerPath, yarn.LayerMetadata{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(summer.SumCall.CallCount).To(Equal(0))
+		})
+
+		context("when the metadata records a content checksum", func() {
+			it("compares it against the layer contents", func() {
+				err := checker.Validate(layerPath, yarn.LayerMetadata{ContentChecksum: "some-content-checksum"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(summer.SumCall.Receives.Path).To(Equal(filepath.Join(layerPath, "bin")))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when bin/yarn is missing", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(layerPath, "bin", "yarn"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := checker.Validate(layerPath, yarn.LayerMetadata{})
+					Expect(err).To(MatchError("missing bin/yarn"))
+				})
+			})
+
+			context("when the layer contents were not restored", func() {
+				it("returns an error", func() {
+					err := checker.Validate(filepath.Join(layerPath, "missing"), yarn.LayerMetadata{})
+					Expect(err).To(MatchError("missing bin/yarn"))
+				})
+			})
+
+			context("when bin/yarn is not executable", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(layerPath, "bin", "yarn"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := checker.Validate(layerPath, yarn.LayerMetadata{})
+					Expect(err).To(MatchError("bin/yarn is not executable"))
+				})
+			})
+
+			context("when the content checksum has changed", func() {
+				it("returns an error", func() {
+					err := checker.Validate(layerPath, yarn.LayerMetadata{ContentChecksum: "other-content-checksum"})
+					Expect(err).To(MatchError("content checksum changed (other-content-checksum -> some-content-checksum)"))
+				})
+			})
+
+			context("when the layer contents cannot be checksummed", func() {
+				it.Before(func() {
+					summer.SumCall.Returns.Error = errors.New("failed to sum")
+				})
+
+				it("returns an error", func() {
+					err := checker.Validate(layerPath, yarn.LayerMetadata{ContentChecksum: "some-content-checksum"})
+					Expect(err).To(MatchError("failed to sum"))
+				})
+			})
+		})
+	})
+}
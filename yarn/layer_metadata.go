@@ -0,0 +1,137 @@
+package yarn
+
+// LayerMetadataSchemaVersion is the schema version written by this
+// buildpack. Bump it whenever the layer metadata changes shape, which
+// invalidates layers built with the previous schema.
+const LayerMetadataSchemaVersion = 2
+
+const (
+	metadataKeySchemaVersion    = "schema_version"
+	metadataKeyBuiltAt          = "built_at"
+	metadataKeyDependencySHA    = "dependency_sha"
+	metadataKeyYarnVersion      = "yarn_version"
+	metadataKeyStack            = "stack"
+	metadataKeyBuildpackVersion = "buildpack_version"
+	metadataKeyNodeVersion      = "node_version"
+	metadataKeyLockfileChecksum = "lockfile_checksum"
+	metadataKeyContentChecksum  = "content_checksum"
+	metadataKeyPhaseTimings     = "phase_timings"
+)
+
+type LayerMetadata struct {
+	SchemaVersion    int
+	BuiltAt          string
+	DependencySHA    string
+	YarnVersion      string
+	Stack            string
+	BuildpackVersion string
+	NodeVersion      string
+	LockfileChecksum string
+	ContentChecksum  string
+
+	// PhaseTimings holds the duration in milliseconds of each phase of the
+	// most recent build that used the layer. It is informational and never
+	// affects reuse.
+	PhaseTimings map[string]int64
+}
+
+// ParseLayerMetadata reads the metadata of a previously built layer. Metadata
+// written before schema versions existed is treated as schema version 1, and
+// metadata from any schema version other than the current one is returned
+// with only its SchemaVersion set so that it never matches.
+func ParseLayerMetadata(raw map[string]interface{}) LayerMetadata {
+	if len(raw) == 0 {
+		return LayerMetadata{SchemaVersion: LayerMetadataSchemaVersion}
+	}
+
+	version := 1
+	if _, ok := raw[metadataKeySchemaVersion]; ok {
+		var ok bool
+		version, ok = toInt(raw[metadataKeySchemaVersion])
+		if !ok {
+			return LayerMetadata{}
+		}
+	}
+
+	if version != LayerMetadataSchemaVersion {
+		return LayerMetadata{SchemaVersion: version}
+	}
+
+	return LayerMetadata{
+		SchemaVersion:    version,
+		BuiltAt:          toString(raw[metadataKeyBuiltAt]),
+		DependencySHA:    toString(raw[metadataKeyDependencySHA]),
+		YarnVersion:      toString(raw[metadataKeyYarnVersion]),
+		Stack:            toString(raw[metadataKeyStack]),
+		BuildpackVersion: toString(raw[metadataKeyBuildpackVersion]),
+		NodeVersion:      toString(raw[metadataKeyNodeVersion]),
+		LockfileChecksum: toString(raw[metadataKeyLockfileChecksum]),
+		ContentChecksum:  toString(raw[metadataKeyContentChecksum]),
+		PhaseTimings:     toTimings(raw[metadataKeyPhaseTimings]),
+	}
+}
+
+// empty reports whether the metadata is that of a layer that has never been
+// built, which ParseLayerMetadata returns for missing metadata.
+func (m LayerMetadata) empty() bool {
+	return m.SchemaVersion == LayerMetadataSchemaVersion &&
+		m.BuiltAt == "" &&
+		m.DependencySHA == "" &&
+		m.BuildpackVersion == "" &&
+		m.Stack == ""
+}
+
+// Map returns the metadata in the form stored in the layer TOML file.
+func (m LayerMetadata) Map() map[string]interface{} {
+	raw := map[string]interface{}{
+		metadataKeySchemaVersion:    m.SchemaVersion,
+		metadataKeyBuiltAt:          m.BuiltAt,
+		metadataKeyDependencySHA:    m.DependencySHA,
+		metadataKeyYarnVersion:      m.YarnVersion,
+		metadataKeyStack:            m.Stack,
+		metadataKeyBuildpackVersion: m.BuildpackVersion,
+		metadataKeyNodeVersion:      m.NodeVersion,
+		metadataKeyLockfileChecksum: m.LockfileChecksum,
+		metadataKeyContentChecksum:  m.ContentChecksum,
+	}
+
+	if len(m.PhaseTimings) > 0 {
+		raw[metadataKeyPhaseTimings] = m.PhaseTimings
+	}
+
+	return raw
+}
+
+func toString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+func toTimings(value interface{}) map[string]int64 {
+	switch v := value.(type) {
+	case map[string]int64:
+		return v
+	case map[string]interface{}:
+		timings := map[string]int64{}
+		for phase, duration := range v {
+			if ms, ok := toInt(duration); ok {
+				timings[phase] = int64(ms)
+			}
+		}
+
+		return timings
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package yarn_test
+
+import (
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLayerMetadata(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("ParseLayerMetadata", func() {
+		it("parses metadata written with the current schema version", func() {
+			metadata := yarn.ParseLayerMetadata(map[string]interface{}{
+				"schema_version":    int64(2),
+				"built_at":          "some-time",
+				"dependency_sha":    "some-sha",
+				"yarn_version":      "some-yarn-version",
+				"stack":             "some-stack",
+				"buildpack_version": "some-buildpack-version",
+				"node_version":      "some-node-version",
+				"lockfile_checksum": "some-lockfile-checksum",
+				"phase_timings": map[string]interface{}{
+					"resolve": int64(12),
+					"sbom":    int64(3),
+				},
+			})
+			Expect(metadata).To(Equal(yarn.LayerMetadata{
+				SchemaVersion:    2,
+				BuiltAt:          "some-time",
+				DependencySHA:    "some-sha",
+				YarnVersion:      "some-yarn-version",
+				Stack:            "some-stack",
+				BuildpackVersion: "some-buildpack-version",
+				NodeVersion:      "some-node-version",
+				LockfileChecksum: "some-lockfile-checksum",
+				PhaseTimings: map[string]int64{
+					"resolve": 12,
+					"sbom":    3,
+				},
+			}))
+		})
+
+		context("when the layer has no metadata", func() {
+			it("returns empty metadata with the current schema version", func() {
+				Expect(yarn.ParseLayerMetadata(nil)).To(Equal(yarn.LayerMetadata{SchemaVersion: 2}))
+			})
+		})
+
+		context("when the metadata predates schema versions", func() {
+			it("returns metadata with schema version 1 so that it never matches", func() {
+				metadata := yarn.ParseLayerMetadata(map[string]interface{}{
+					"built_at":  "some-time",
+					"cache_sha": "some-sha",
+				})
+				Expect(metadata).To(Equal(yarn.LayerMetadata{SchemaVersion: 1}))
+
+				match, mismatch := yarn.NewCacheHandler().Match(metadata, yarn.CacheKey{
+					SHA:              "some-sha",
+					BuildpackVersion: "some-buildpack-version",
+					Stack:            "some-stack",
+				})
+				Expect(match).To(BeFalse())
+				Expect(mismatch.String()).To(Equal("metadata schema version changed (1 -> 2)"))
+			})
+		})
+
+		context("when the metadata was written by a newer schema version", func() {
+			it("returns metadata with only the schema version set", func() {
+				metadata := yarn.ParseLayerMetadata(map[string]interface{}{
+					"schema_version": int64(3),
+					"dependency_sha": "some-sha",
+				})
+				Expect(metadata).To(Equal(yarn.LayerMetadata{SchemaVersion: 3}))
+			})
+		})
+
+		context("when the schema version is malformed", func() {
+			it("returns empty metadata", func() {
+				metadata := yarn.ParseLayerMetadata(map[string]interface{}{
+					"schema_version": "two",
+					"dependency_sha": "some-sha",
+				})
+				Expect(metadata).To(Equal(yarn.LayerMetadata{}))
+			})
+		})
+	})
+
+	context("Map", func() {
+		it("returns the metadata as a map that can be parsed again", func() {
+			metadata := yarn.LayerMetadata{
+				SchemaVersion:    2,
+				BuiltAt:          "some-time",
+				DependencySHA:    "some-sha",
+				YarnVersion:      "some-yarn-version",
+				Stack:            "some-stack",
+				BuildpackVersion: "some-buildpack-version",
+				NodeVersion:      "some-node-version",
+				LockfileChecksum: "some-lockfile-checksum",
+				ContentChecksum:  "some-content-checksum",
+				PhaseTimings: map[string]int64{
+					"resolve": 12,
+				},
+			}
+			Expect(yarn.ParseLayerMetadata(metadata.Map())).To(Equal(metadata))
+		})
+	})
+}
@@ -0,0 +1,172 @@
+package yarn
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseIdentifier matches the shape of an SPDX license or exception
+// identifier, including LicenseRef- references and the "+" suffix meaning
+// "or any later version".
+var licenseIdentifier = regexp.MustCompile(`^((DocumentRef-[A-Za-z0-9.-]+:)?LicenseRef-[A-Za-z0-9.-]+|[A-Za-z0-9][A-Za-z0-9.-]*\+?)$`)
+
+// nonSPDXLicenses are values found in package.json license fields that have
+// the shape of an identifier but do not name a license.
+var nonSPDXLicenses = map[string]bool{
+	"UNKNOWN":     true,
+	"UNLICENSED":  true,
+	"NONE":        true,
+	"PROPRIETARY": true,
+	"CUSTOM":      true,
+	"COMMERCIAL":  true,
+}
+
+// licenseExpression is a parsed SPDX license expression. Leaves hold a license
+// and an optional WITH exception, while inner nodes combine their operands
+// with AND or OR.
+type licenseExpression struct {
+	operator  string
+	operands  []licenseExpression
+	license   string
+	exception string
+}
+
+// parseLicenseExpression parses an SPDX license expression, where WITH binds
+// tighter than AND, which binds tighter than OR. It reports false for
+// anything that is not a valid expression of SPDX identifiers, such as
+// "SEE LICENSE IN LICENSE.md" or "UNLICENSED".
+func parseLicenseExpression(expression string) (licenseExpression, bool) {
+	expression = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expression)
+
+	parser := licenseParser{tokens: strings.Fields(expression)}
+	parsed, ok := parser.parseOr()
+	if !ok || parser.position != len(parser.tokens) {
+		return licenseExpression{}, false
+	}
+
+	return parsed, true
+}
+
+type licenseParser struct {
+	tokens   []string
+	position int
+}
+
+func (p *licenseParser) next() string {
+	if p.position >= len(p.tokens) {
+		return ""
+	}
+
+	token := p.tokens[p.position]
+	p.position++
+
+	return token
+}
+
+func (p *licenseParser) acceptOperator(operator string) bool {
+	if p.position < len(p.tokens) && strings.EqualFold(p.tokens[p.position], operator) {
+		p.position++
+		return true
+	}
+
+	return false
+}
+
+func (p *licenseParser) parseOr() (licenseExpression, bool) {
+	return p.parseBinary("OR", p.parseAnd)
+}
+
+func (p *licenseParser) parseAnd() (licenseExpression, bool) {
+	return p.parseBinary("AND", p.parseTerm)
+}
+
+func (p *licenseParser) parseBinary(operator string, parseOperand func() (licenseExpression, bool)) (licenseExpression, bool) {
+	operand, ok := parseOperand()
+	if !ok {
+		return licenseExpression{}, false
+	}
+
+	operands := []licenseExpression{operand}
+	for p.acceptOperator(operator) {
+		operand, ok := parseOperand()
+		if !ok {
+			return licenseExpression{}, false
+		}
+
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], true
+	}
+
+	return licenseExpression{operator: operator, operands: operands}, true
+}
+
+func (p *licenseParser) parseTerm() (licenseExpression, bool) {
+	token := p.next()
+	if token == "(" {
+		inner, ok := p.parseOr()
+		if !ok || p.next() != ")" {
+			return licenseExpression{}, false
+		}
+
+		return inner, true
+	}
+
+	if !isLicenseIdentifier(token) {
+		return licenseExpression{}, false
+	}
+
+	term := licenseExpression{license: token}
+	if p.acceptOperator("WITH") {
+		exception := p.next()
+		if !isLicenseIdentifier(exception) {
+			return licenseExpression{}, false
+		}
+
+		term.exception = exception
+	}
+
+	return term, true
+}
+
+func isLicenseIdentifier(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", "WITH":
+		return false
+	}
+
+	return licenseIdentifier.MatchString(token) && !nonSPDXLicenses[strings.ToUpper(token)]
+}
+
+// permittedBy reports whether the policy accepts the expression. Every
+// operand of an AND must be acceptable, while only one operand of an OR needs
+// to be.
+func (e licenseExpression) permittedBy(policy LicensePolicy) bool {
+	switch e.operator {
+	case "AND":
+		for _, operand := range e.operands {
+			if !operand.permittedBy(policy) {
+				return false
+			}
+		}
+
+		return true
+	case "OR":
+		for _, operand := range e.operands {
+			if operand.permittedBy(policy) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		names := []string{e.license}
+		if e.exception != "" {
+			names = append(names, e.license+" WITH "+e.exception)
+		}
+
+		return policy.permitsLicense(names)
+	}
+}
@@ -0,0 +1,463 @@
+package yarn
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const UnknownLicense = "UNKNOWN"
+
+type LicensePolicy struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Enabled reports whether any allow or deny rules have been configured.
+func (p LicensePolicy) Enabled() bool {
+	return len(p.Allow) > 0 || len(p.Deny) > 0
+}
+
+// Permits reports whether an SPDX license expression is acceptable. Every
+// license in an AND expression must be acceptable, while only one license in
+// an OR expression needs to be. A license with a WITH exception is matched
+// both with and without the exception, so denying GPL-* also denies
+// "GPL-3.0-only WITH GCC-exception-3.1". Unknown licenses, and anything that
+// is not a valid SPDX expression, are never acceptable.
+func (p LicensePolicy) Permits(expression string) bool {
+	parsed, ok := parseLicenseExpression(expression)
+	if !ok {
+		return false
+	}
+
+	return parsed.permittedBy(p)
+}
+
+// permitsLicense reports whether a single license, given by each of the names
+// it can be matched by, is acceptable.
+func (p LicensePolicy) permitsLicense(names []string) bool {
+	for _, pattern := range p.Deny {
+		for _, name := range names {
+			if matchLicense(pattern, name) {
+				return false
+			}
+		}
+	}
+
+	if len(p.Allow) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Allow {
+		for _, name := range names {
+			if matchLicense(pattern, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchLicense compares a license against a shell pattern such as GPL-*,
+// ignoring case.
+func matchLicense(pattern, license string) bool {
+	match, err := path.Match(strings.ToLower(pattern), strings.ToLower(license))
+	return err == nil && match
+}
+
+type LicenseViolation struct {
+	Name    string
+	Version string
+	License string
+}
+
+type LicenseReport struct {
+	// Source describes where the licenses were read from. It is empty when
+	// there was nothing to read them from and no packages were scanned.
+	Source string
+
+	// Licenses maps each license expression to the name@version of every
+	// installed package declaring it.
+	Licenses   map[string][]string
+	Violations []LicenseViolation
+}
+
+type LicenseScanner struct {
+	packageJSONParser PackageJSONParser
+}
+
+func NewLicenseScanner() LicenseScanner {
+	return LicenseScanner{
+		packageJSONParser: NewPackageJSONParser(),
+	}
+}
+
+// Scan reads the license of every package and checks the production packages
+// against the policy. Packages that cannot be found in yarn.lock are treated
+// as production packages. This buildpack runs before packages are installed,
+// so licenses are read from a node_modules directory the application ships,
+// or else from the package archives in its yarn cache or offline mirror.
+// Lockfile packages missing from those archives have an unknown license.
+// When there is none of these, an empty report without a Source is returned.
+func (s LicenseScanner) Scan(workingDir string, entries []LockfileEntry, policy LicensePolicy) (LicenseReport, error) {
+	modules := filepath.Join(workingDir, "node_modules")
+	_, err := os.Stat(modules)
+	if err != nil && !os.IsNotExist(err) {
+		return LicenseReport{}, fmt.Errorf("failed to scan licenses: %w", err)
+	}
+
+	var (
+		installed []installedPackage
+		source    string
+	)
+	if err == nil {
+		source = "node_modules"
+		installed, err = findInstalledPackages(modules)
+	} else {
+		source, installed, err = s.findArchivedPackages(workingDir, entries)
+	}
+	if err != nil {
+		return LicenseReport{}, fmt.Errorf("failed to scan licenses: %w", err)
+	}
+
+	if source == "" {
+		return LicenseReport{}, nil
+	}
+
+	pkg, err := s.packageJSONParser.Parse(filepath.Join(workingDir, "package.json"))
+	if err != nil {
+		return LicenseReport{}, fmt.Errorf("failed to scan licenses: %w", err)
+	}
+
+	development := map[string]bool{}
+	for _, p := range resolveDependencyGraph(pkg, entries) {
+		if p.scope == scopeDevelopment {
+			development[p.entry.Name+"@"+p.entry.Version] = true
+		}
+	}
+
+	report := LicenseReport{Source: source, Licenses: map[string][]string{}}
+	for _, p := range installed {
+		id := p.name + "@" + p.version
+		report.Licenses[p.license] = append(report.Licenses[p.license], id)
+
+		if !development[id] && !policy.Permits(p.license) {
+			report.Violations = append(report.Violations, LicenseViolation{
+				Name:    p.name,
+				Version: p.version,
+				License: p.license,
+			})
+		}
+	}
+
+	for _, ids := range report.Licenses {
+		sort.Strings(ids)
+	}
+
+	sort.Slice(report.Violations, func(i, j int) bool {
+		return report.Violations[i].Name < report.Violations[j].Name
+	})
+
+	return report, nil
+}
+
+// findArchivedPackages reads the license of every npm package in yarn.lock
+// from its archive in the yarn v2+ project cache, or in the yarn v1 offline
+// mirror. It returns no source when the lockfile format has no such
+// directory in the working directory.
+func (s LicenseScanner) findArchivedPackages(workingDir string, entries []LockfileEntry) (string, []installedPackage, error) {
+	config, err := NewYarnrcParser().Parse(workingDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	berry := false
+	for _, entry := range entries {
+		if entry.Resolution != "" {
+			berry = true
+			break
+		}
+	}
+
+	dir := config.Classic.OfflineMirror
+	if berry {
+		dir = config.Berry.ProjectCache()
+	}
+
+	if dir == "" {
+		return "", nil, nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	if !info.IsDir() {
+		return "", nil, nil
+	}
+
+	var packages []installedPackage
+	for _, entry := range entries {
+		var (
+			archive      string
+			readManifest = readTarballManifest
+		)
+		if berry {
+			if entry.Resolution != "" && !strings.HasPrefix(entry.Resolution, entry.Name+"@npm:") {
+				continue
+			}
+
+			archives, err := berryArchives(dir, entry)
+			if err != nil {
+				return "", nil, err
+			}
+
+			if len(archives) > 0 {
+				archive = archives[0]
+			}
+			readManifest = readZipManifest(entry.Name)
+		} else {
+			archive = mirrorTarball(dir, entry)
+			if archive == "" {
+				continue
+			}
+		}
+
+		var license string
+		if archive != "" {
+			license, err = readArchiveLicense(archive, readManifest)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		if license == "" {
+			license = UnknownLicense
+		}
+
+		packages = append(packages, installedPackage{
+			name:    entry.Name,
+			version: entry.Version,
+			license: license,
+		})
+	}
+
+	source, err := filepath.Rel(workingDir, dir)
+	if err != nil || strings.HasPrefix(source, "..") {
+		source = dir
+	}
+
+	return source, packages, nil
+}
+
+// readArchiveLicense returns the license declared by the package.json in a
+// package archive, or nothing when the archive does not exist.
+func readArchiveLicense(archive string, readManifest func(string) ([]byte, error)) (string, error) {
+	content, err := readManifest(archive)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if content == nil {
+		return UnknownLicense, nil
+	}
+
+	p, err := parseInstalledPackage(content, archive)
+	if err != nil {
+		return "", err
+	}
+
+	return p.license, nil
+}
+
+// readZipManifest reads the package.json of a package from a yarn v2+ cache
+// archive, which keeps it under node_modules/<name>.
+func readZipManifest(name string) func(string) ([]byte, error) {
+	return func(archive string) ([]byte, error) {
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		for _, file := range reader.File {
+			if file.Name != "node_modules/"+name+"/package.json" {
+				continue
+			}
+
+			content, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer content.Close()
+
+			return ioutil.ReadAll(content)
+		}
+
+		return nil, nil
+	}
+}
+
+// readTarballManifest reads the package.json at the top of the package
+// directory in an npm tarball, which is usually named package.
+func readTarballManifest(archive string) ([]byte, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", archive, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", archive, err)
+		}
+
+		parts := strings.Split(path.Clean(strings.TrimPrefix(header.Name, "./")), "/")
+		if len(parts) == 2 && parts[1] == "package.json" {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+type installedPackage struct {
+	name    string
+	version string
+	license string
+}
+
+// findInstalledPackages walks a node_modules directory, including scoped
+// packages and nested node_modules directories.
+func findInstalledPackages(dir string) ([]installedPackage, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var packages []installedPackage
+	for _, info := range infos {
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+
+		if strings.HasPrefix(info.Name(), "@") {
+			scoped, err := findInstalledPackages(filepath.Join(dir, info.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			packages = append(packages, scoped...)
+			continue
+		}
+
+		p, err := readInstalledPackage(filepath.Join(dir, info.Name(), "package.json"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		packages = append(packages, p)
+
+		nested, err := findInstalledPackages(filepath.Join(dir, info.Name(), "node_modules"))
+		if err != nil {
+			return nil, err
+		}
+
+		packages = append(packages, nested...)
+	}
+
+	return packages, nil
+}
+
+func readInstalledPackage(path string) (installedPackage, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return installedPackage{}, err
+	}
+
+	return parseInstalledPackage(content, path)
+}
+
+func parseInstalledPackage(content []byte, path string) (installedPackage, error) {
+	var pkg struct {
+		Name     string          `json:"name"`
+		Version  string          `json:"version"`
+		License  json.RawMessage `json:"license"`
+		Licenses []struct {
+			Type string `json:"type"`
+		} `json:"licenses"`
+	}
+
+	err := json.Unmarshal(content, &pkg)
+	if err != nil {
+		return installedPackage{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	// The license field is usually an SPDX expression, but older packages use
+	// an object with a type or a list of such objects under "licenses".
+	var license string
+	if json.Unmarshal(pkg.License, &license) != nil {
+		var object struct {
+			Type string `json:"type"`
+		}
+
+		if json.Unmarshal(pkg.License, &object) == nil {
+			license = object.Type
+		}
+	}
+
+	if license == "" && len(pkg.Licenses) > 0 {
+		var types []string
+		for _, l := range pkg.Licenses {
+			types = append(types, l.Type)
+		}
+
+		license = strings.Join(types, " OR ")
+	}
+
+	if license == "" {
+		license = UnknownLicense
+	}
+
+	return installedPackage{
+		name:    pkg.Name,
+		version: pkg.Version,
+		license: license,
+	}, nil
+}
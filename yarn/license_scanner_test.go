@@ -0,0 +1,307 @@
+package yarn_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLicenseScanner(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		entries    []yarn.LockfileEntry
+		scanner    yarn.LicenseScanner
+	)
+
+	writePackage := func(dir, content string) {
+		Expect(os.MkdirAll(dir, os.ModePerm)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "package.json"), []byte(content), 0644)).To(Succeed())
+	}
+
+	writeZip := func(path, name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+
+		buffer := bytes.NewBuffer(nil)
+		zw := zip.NewWriter(buffer)
+		w, err := zw.Create(name)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+
+		Expect(ioutil.WriteFile(path, buffer.Bytes(), 0644)).To(Succeed())
+	}
+
+	writeTarball := func(path, name, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+
+		buffer := bytes.NewBuffer(nil)
+		gw := gzip.NewWriter(buffer)
+		tw := tar.NewWriter(gw)
+		Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tw.Close()).To(Succeed())
+		Expect(gw.Close()).To(Succeed())
+
+		Expect(ioutil.WriteFile(path, buffer.Bytes(), 0644)).To(Succeed())
+	}
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		writePackage(workingDir, `{
+			"name": "some-app",
+			"dependencies": {
+				"@scope/prod": "^1.0.0",
+				"legacy": "^1.0.0"
+			},
+			"devDependencies": {
+				"dev": "^2.0.0"
+			}
+		}`)
+
+		modules := filepath.Join(workingDir, "node_modules")
+		writePackage(filepath.Join(modules, "@scope", "prod"), `{"name": "@scope/prod", "version": "1.2.3", "license": "MIT"}`)
+		writePackage(filepath.Join(modules, "@scope", "prod", "node_modules", "nested"), `{"name": "nested", "version": "3.0.0", "license": "(MIT OR GPL-3.0)"}`)
+		writePackage(filepath.Join(modules, "legacy"), `{"name": "legacy", "version": "1.0.0", "licenses": [{"type": "BSD-3-Clause"}]}`)
+		writePackage(filepath.Join(modules, "dev"), `{"name": "dev", "version": "2.1.0", "license": {"type": "GPL-3.0"}}`)
+		writePackage(filepath.Join(modules, "unlicensed"), `{"name": "unlicensed", "version": "0.1.0"}`)
+		Expect(os.MkdirAll(filepath.Join(modules, ".bin"), os.ModePerm)).To(Succeed())
+
+		entries = []yarn.LockfileEntry{
+			{
+				Name:         "@scope/prod",
+				Descriptors:  []string{"@scope/prod@^1.0.0"},
+				Version:      "1.2.3",
+				Dependencies: map[string]string{"nested": "^3.0.0"},
+			},
+			{
+				Name:        "nested",
+				Descriptors: []string{"nested@^3.0.0"},
+				Version:     "3.0.0",
+			},
+			{
+				Name:        "legacy",
+				Descriptors: []string{"legacy@^1.0.0"},
+				Version:     "1.0.0",
+			},
+			{
+				Name:        "dev",
+				Descriptors: []string{"dev@^2.0.0"},
+				Version:     "2.1.0",
+			},
+		}
+
+		scanner = yarn.NewLicenseScanner()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("groups the installed packages by license and reports production violations", func() {
+		report, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{
+			Allow: []string{"mit", "BSD-*"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Source).To(Equal("node_modules"))
+		Expect(report.Licenses).To(Equal(map[string][]string{
+			"MIT":               []string{"@scope/prod@1.2.3"},
+			"(MIT OR GPL-3.0)":  []string{"nested@3.0.0"},
+			"BSD-3-Clause":      []string{"legacy@1.0.0"},
+			"GPL-3.0":           []string{"dev@2.1.0"},
+			yarn.UnknownLicense: []string{"unlicensed@0.1.0"},
+		}))
+		Expect(report.Violations).To(Equal([]yarn.LicenseViolation{
+			{Name: "unlicensed", Version: "0.1.0", License: yarn.UnknownLicense},
+		}))
+	})
+
+	context("when a license is denied", func() {
+		it("reports production packages that cannot avoid it", func() {
+			report, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{
+				Deny: []string{"GPL-*", "MIT"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Violations).To(Equal([]yarn.LicenseViolation{
+				{Name: "@scope/prod", Version: "1.2.3", License: "MIT"},
+				{Name: "nested", Version: "3.0.0", License: "(MIT OR GPL-3.0)"},
+				{Name: "unlicensed", Version: "0.1.0", License: yarn.UnknownLicense},
+			}))
+		})
+	})
+
+	context("when the packages are not installed", func() {
+		it.Before(func() {
+			Expect(os.RemoveAll(filepath.Join(workingDir, "node_modules"))).To(Succeed())
+		})
+
+		context("when the application uses a yarn v2+ cache", func() {
+			it.Before(func() {
+				for i := range entries {
+					entries[i].Resolution = entries[i].Name + "@npm:" + entries[i].Version
+				}
+				entries = append(entries, yarn.LockfileEntry{
+					Name:       "some-app",
+					Version:    "0.0.0-use.local",
+					Resolution: "some-app@workspace:.",
+				})
+
+				cache := filepath.Join(workingDir, ".yarn", "cache")
+				writeZip(filepath.Join(cache, "@scope-prod-npm-1.2.3-abc-abc.zip"), "node_modules/@scope/prod/package.json", `{"name": "@scope/prod", "version": "1.2.3", "license": "MIT"}`)
+				writeZip(filepath.Join(cache, "nested-npm-3.0.0-abc-abc.zip"), "node_modules/nested/package.json", `{"name": "nested", "version": "3.0.0", "license": "(MIT OR GPL-3.0)"}`)
+				writeZip(filepath.Join(cache, "dev-npm-2.1.0-abc-abc.zip"), "node_modules/dev/package.json", `{"name": "dev", "version": "2.1.0", "license": {"type": "GPL-3.0"}}`)
+			})
+
+			it("reads the licenses from the cache archives", func() {
+				report, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{
+					Allow: []string{"MIT", "BSD-*"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Source).To(Equal(filepath.Join(".yarn", "cache")))
+				Expect(report.Licenses).To(Equal(map[string][]string{
+					"MIT":               []string{"@scope/prod@1.2.3"},
+					"(MIT OR GPL-3.0)":  []string{"nested@3.0.0"},
+					"GPL-3.0":           []string{"dev@2.1.0"},
+					yarn.UnknownLicense: []string{"legacy@1.0.0"},
+				}))
+				Expect(report.Violations).To(Equal([]yarn.LicenseViolation{
+					{Name: "legacy", Version: "1.0.0", License: yarn.UnknownLicense},
+				}))
+			})
+		})
+
+		context("when the application uses a yarn v1 offline mirror", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc"), []byte(`yarn-offline-mirror "./mirror"`), 0644)).To(Succeed())
+
+				for i := range entries {
+					base := entries[i].Name
+					if i := strings.Index(base, "/"); i >= 0 {
+						base = base[i+1:]
+					}
+					entries[i].Resolved = fmt.Sprintf("https://registry.yarnpkg.com/%s/-/%s-%s.tgz", entries[i].Name, base, entries[i].Version)
+				}
+
+				mirror := filepath.Join(workingDir, "mirror")
+				writeTarball(filepath.Join(mirror, "@scope-prod-1.2.3.tgz"), "package/package.json", `{"name": "@scope/prod", "version": "1.2.3", "license": "MIT"}`)
+				writeTarball(filepath.Join(mirror, "nested-3.0.0.tgz"), "package/package.json", `{"name": "nested", "version": "3.0.0", "license": "(MIT OR GPL-3.0)"}`)
+				writeTarball(filepath.Join(mirror, "legacy-1.0.0.tgz"), "legacy/package.json", `{"name": "legacy", "version": "1.0.0", "licenses": [{"type": "BSD-3-Clause"}]}`)
+			})
+
+			it("reads the licenses from the mirrored tarballs", func() {
+				report, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{
+					Allow: []string{"MIT", "BSD-*"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Source).To(Equal("mirror"))
+				Expect(report.Licenses).To(Equal(map[string][]string{
+					"MIT":               []string{"@scope/prod@1.2.3"},
+					"(MIT OR GPL-3.0)":  []string{"nested@3.0.0"},
+					"BSD-3-Clause":      []string{"legacy@1.0.0"},
+					yarn.UnknownLicense: []string{"dev@2.1.0"},
+				}))
+				Expect(report.Violations).To(BeEmpty())
+			})
+		})
+
+		context("when there is nothing to read licenses from", func() {
+			it("returns a report without a source", func() {
+				report, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{Deny: []string{"GPL-*"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(yarn.LicenseReport{}))
+			})
+		})
+	})
+
+	context("LicensePolicy.Permits", func() {
+		it("respects parentheses and operator precedence", func() {
+			policy := yarn.LicensePolicy{Allow: []string{"MIT"}}
+			Expect(policy.Permits("MIT OR (GPL-2.0 AND BSD-3-Clause)")).To(BeTrue())
+			Expect(policy.Permits("(GPL-2.0 AND BSD-3-Clause) OR MIT")).To(BeTrue())
+			Expect(policy.Permits("MIT AND (GPL-2.0 OR BSD-3-Clause)")).To(BeFalse())
+			Expect(policy.Permits("MIT AND GPL-2.0 OR MIT")).To(BeTrue())
+		})
+
+		it("matches a license with an exception by the license itself", func() {
+			Expect(yarn.LicensePolicy{Deny: []string{"GPL-*"}}.Permits("GPL-3.0-only WITH GCC-exception-3.1")).To(BeFalse())
+			Expect(yarn.LicensePolicy{Allow: []string{"GPL-3.0-only WITH GCC-exception-3.1"}}.Permits("GPL-3.0-only WITH GCC-exception-3.1")).To(BeTrue())
+			Expect(yarn.LicensePolicy{Allow: []string{"Apache-2.0"}}.Permits("Apache-2.0 WITH LLVM-exception")).To(BeTrue())
+		})
+
+		it("treats anything that is not an SPDX expression as unknown", func() {
+			policy := yarn.LicensePolicy{Deny: []string{"GPL-*"}}
+			Expect(policy.Permits("SEE LICENSE IN LICENSE.md")).To(BeFalse())
+			Expect(policy.Permits("UNLICENSED")).To(BeFalse())
+			Expect(policy.Permits(yarn.UnknownLicense)).To(BeFalse())
+			Expect(policy.Permits("(MIT")).To(BeFalse())
+			Expect(policy.Permits("MIT OR")).To(BeFalse())
+			Expect(policy.Permits("MIT WITH")).To(BeFalse())
+			Expect(policy.Permits("")).To(BeFalse())
+
+			Expect(policy.Permits("MIT")).To(BeTrue())
+			Expect(policy.Permits("LicenseRef-some-license")).To(BeTrue())
+		})
+	})
+
+	context("failure cases", func() {
+		context("when a cache archive is malformed", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(workingDir, "node_modules"))).To(Succeed())
+				entries[0].Resolution = "@scope/prod@npm:1.2.3"
+
+				cache := filepath.Join(workingDir, ".yarn", "cache")
+				Expect(os.MkdirAll(cache, os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(cache, "@scope-prod-npm-1.2.3-abc-abc.zip"), []byte("not a zip"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{Deny: []string{"GPL-*"}})
+				Expect(err).To(MatchError(ContainSubstring("failed to scan licenses:")))
+			})
+		})
+
+		context("when an installed package.json is malformed", func() {
+			it.Before(func() {
+				writePackage(filepath.Join(workingDir, "node_modules", "broken"), `%%%`)
+			})
+
+			it("returns an error", func() {
+				_, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{Deny: []string{"GPL-*"}})
+				Expect(err).To(MatchError(ContainSubstring("failed to scan licenses:")))
+			})
+		})
+
+		context("when the package.json is malformed", func() {
+			it.Before(func() {
+				writePackage(workingDir, `%%%`)
+			})
+
+			it("returns an error", func() {
+				_, err := scanner.Scan(workingDir, entries, yarn.LicensePolicy{Deny: []string{"GPL-*"}})
+				Expect(err).To(MatchError(ContainSubstring("failed to scan licenses:")))
+			})
+		})
+	})
+}
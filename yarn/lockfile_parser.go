@@ -0,0 +1,275 @@
+package yarn
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LockfileEntry is a single resolved package in a yarn.lock file. Resolved
+// and Integrity are only written by yarn v1, while Resolution and Checksum
+// are only written by yarn v2+ (Berry). Name is the package that was
+// resolved, and Alias is the name it is installed under when the descriptors
+// alias it, as in string-width-cjs@npm:string-width@^4.2.0.
+type LockfileEntry struct {
+	Name         string
+	Alias        string
+	Descriptors  []string
+	Version      string
+	Resolved     string
+	Resolution   string
+	Integrity    string
+	Checksum     string
+	Dependencies map[string]string
+	Line         int
+}
+
+type LockfileParser struct{}
+
+func NewLockfileParser() LockfileParser {
+	return LockfileParser{}
+}
+
+// Parse reads a yarn.lock file written by either yarn v1 or yarn v2+ (Berry)
+// and returns one entry per resolved package in the order they appear in the
+// file.
+func (p LockfileParser) Parse(path string) ([]LockfileEntry, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LockfileEntry
+	if isBerryLockfile(content) {
+		entries, err = parseBerryLockfile(content)
+	} else {
+		entries, err = parseClassicLockfile(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse yarn.lock: %w", err)
+	}
+
+	return entries, nil
+}
+
+func isBerryLockfile(content []byte) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "__metadata:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseClassicLockfile(content []byte) ([]LockfileEntry, error) {
+	var (
+		entries []LockfileEntry
+		current *LockfileEntry
+		section string
+		number  int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		number++
+
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			if current != nil {
+				entries = append(entries, *current)
+			}
+
+			current = &LockfileEntry{Line: number}
+
+			var name string
+			for _, descriptor := range splitDescriptors(strings.TrimSuffix(line, ":")) {
+				var err error
+				name, err = parseDescriptorName(descriptor)
+				if err != nil {
+					return nil, err
+				}
+
+				current.Name = descriptorTarget(descriptor, name)
+				current.Descriptors = append(current.Descriptors, descriptor)
+			}
+
+			if current.Name != name {
+				current.Alias = name
+			}
+
+			section = ""
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+
+		if strings.HasPrefix(line, "    ") {
+			if (section == "dependencies" || section == "optionalDependencies") && len(fields) == 2 {
+				if current.Dependencies == nil {
+					current.Dependencies = map[string]string{}
+				}
+
+				current.Dependencies[strings.Trim(fields[0], `"`)] = strings.Trim(fields[1], `"`)
+			}
+
+			continue
+		}
+
+		if len(fields) != 2 {
+			section = strings.TrimSuffix(fields[0], ":")
+			continue
+		}
+
+		value := strings.Trim(fields[1], `"`)
+		switch fields[0] {
+		case "version":
+			current.Version = value
+		case "resolved":
+			current.Resolved = value
+		case "integrity":
+			current.Integrity = value
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if current != nil {
+		entries = append(entries, *current)
+	}
+
+	return entries, nil
+}
+
+func parseBerryLockfile(content []byte) ([]LockfileEntry, error) {
+	var lockfile map[string]struct {
+		Version              string            `yaml:"version"`
+		Resolution           string            `yaml:"resolution"`
+		Checksum             string            `yaml:"checksum"`
+		Dependencies         map[string]string `yaml:"dependencies"`
+		OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+	}
+
+	err := yaml.Unmarshal(content, &lockfile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The YAML decoder does not expose positions, so the line of each entry is
+	// found by scanning for its top level key.
+	lines := map[string]int{}
+	for number, line := range strings.Split(string(content), "\n") {
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lines[strings.Trim(strings.TrimSuffix(line, ":"), `"`)] = number + 1
+	}
+
+	var entries []LockfileEntry
+	for key, value := range lockfile {
+		if key == "__metadata" {
+			continue
+		}
+
+		entry := LockfileEntry{
+			Version:    value.Version,
+			Resolution: value.Resolution,
+			Checksum:   value.Checksum,
+			Line:       lines[key],
+		}
+
+		var name string
+		for _, descriptor := range splitDescriptors(key) {
+			name, err = parseDescriptorName(descriptor)
+			if err != nil {
+				return nil, err
+			}
+
+			entry.Name = descriptorTarget(descriptor, name)
+			entry.Descriptors = append(entry.Descriptors, descriptor)
+		}
+
+		// The resolution always names the package that was resolved, including
+		// for aliases and patches.
+		if resolved, err := parseDescriptorName(entry.Resolution); err == nil {
+			entry.Name = resolved
+		}
+
+		if entry.Name != name {
+			entry.Alias = name
+		}
+
+		for _, dependencies := range []map[string]string{value.Dependencies, value.OptionalDependencies} {
+			for name, rng := range dependencies {
+				if entry.Dependencies == nil {
+					entry.Dependencies = map[string]string{}
+				}
+
+				entry.Dependencies[name] = rng
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Line < entries[j].Line
+	})
+
+	return entries, nil
+}
+
+func splitDescriptors(key string) []string {
+	var descriptors []string
+	for _, descriptor := range strings.Split(key, ",") {
+		descriptors = append(descriptors, strings.Trim(strings.TrimSpace(descriptor), `"`))
+	}
+
+	return descriptors
+}
+
+// descriptorTarget returns the name of the package a descriptor resolves to.
+// It is the descriptor name unless the range aliases another package, as in
+// string-width-cjs@npm:string-width@^4.2.0.
+func descriptorTarget(descriptor, name string) string {
+	reference := strings.TrimPrefix(descriptor, name+"@")
+	if strings.HasPrefix(reference, "npm:") {
+		if target, err := parseDescriptorName(strings.TrimPrefix(reference, "npm:")); err == nil {
+			return target
+		}
+	}
+
+	return name
+}
+
+// parseDescriptorName returns the package name from a descriptor such as
+// "@babel/core@^7.0.0", lodash@^4.17.15 or lodash@npm:^4.17.15.
+func parseDescriptorName(descriptor string) (string, error) {
+	index := strings.Index(strings.TrimPrefix(descriptor, "@"), "@")
+	if index <= 0 {
+		return "", fmt.Errorf("invalid package descriptor %q", descriptor)
+	}
+
+	if strings.HasPrefix(descriptor, "@") {
+		index++
+	}
+
+	return descriptor[:index], nil
+}
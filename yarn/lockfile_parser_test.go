@@ -0,0 +1,234 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLockfileParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path   string
+		parser yarn.LockfileParser
+	)
+
+	it.Before(func() {
+		file, err := ioutil.TempFile("", "yarn.lock")
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		_, err = file.WriteString(`# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.8.3":
+  version "7.8.3"
+  resolved "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.8.3.tgz#33e25903d7481181534e12ec0a25f16b6fcf419a"
+  integrity sha512-a9gxpmdXtZEInkCSHUJDLHZVBgb1QS0jhss4cPP93EW7s+uC5bikET2twEF3KV+7rDblJcmNvTR7VJejqd2C2g==
+  dependencies:
+    "@babel/highlight" "^7.8.3"
+
+lodash@^4.17.15:
+  version "4.17.15"
+  resolved "https://registry.yarnpkg.com/lodash/-/lodash-4.17.15.tgz#b447f6670a0455bbfeedd11392eff330ea097548"
+  integrity sha512-8xOcRHvCjnocdS5cpwXQXVzmmh5e5+saE2QGoeQmbKmRS6J3VQppPOIt0MnmE+4xlZoumy0GPG0D0MVIQbNA1A==
+`)
+		Expect(err).NotTo(HaveOccurred())
+
+		path = file.Name()
+
+		parser = yarn.NewLockfileParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	context("Parse", func() {
+		it("returns the resolved packages", func() {
+			entries, err := parser.Parse(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]yarn.LockfileEntry{
+				{
+					Name:        "@babel/code-frame",
+					Descriptors: []string{"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.8.3"},
+					Version:     "7.8.3",
+					Resolved:    "https://registry.yarnpkg.com/@babel/code-frame/-/code-frame-7.8.3.tgz#33e25903d7481181534e12ec0a25f16b6fcf419a",
+					Integrity:   "sha512-a9gxpmdXtZEInkCSHUJDLHZVBgb1QS0jhss4cPP93EW7s+uC5bikET2twEF3KV+7rDblJcmNvTR7VJejqd2C2g==",
+					Dependencies: map[string]string{
+						"@babel/highlight": "^7.8.3",
+					},
+					Line: 5,
+				},
+				{
+					Name:        "lodash",
+					Descriptors: []string{"lodash@^4.17.15"},
+					Version:     "4.17.15",
+					Resolved:    "https://registry.yarnpkg.com/lodash/-/lodash-4.17.15.tgz#b447f6670a0455bbfeedd11392eff330ea097548",
+					Integrity:   "sha512-8xOcRHvCjnocdS5cpwXQXVzmmh5e5+saE2QGoeQmbKmRS6J3VQppPOIt0MnmE+4xlZoumy0GPG0D0MVIQbNA1A==",
+					Line:        12,
+				},
+			}))
+		})
+
+		context("when the yarn.lock was written by yarn v2+", func() {
+			it.Before(func() {
+				err := ioutil.WriteFile(path, []byte(`# This file is generated by running "yarn install" inside your project.
+# Manual changes might be lost - proceed with caution!
+
+__metadata:
+  version: 4
+  cacheKey: 6
+
+"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.8.3":
+  version: 7.8.3
+  resolution: "@babel/code-frame@npm:7.8.3"
+  dependencies:
+    "@babel/highlight": ^7.8.3
+  checksum: 05245d3b22a3ae4439e2b5ef2a8a2c73bd1f2a2f36bc3b2a3ba5cd2b6bbc8e8f
+  languageName: node
+  linkType: hard
+
+"lodash@npm:^4.17.15":
+  version: 4.17.15
+  resolution: "lodash@npm:4.17.15"
+  checksum: 3a25dc2df4e1b3bd00050eebd8ddd64bd8ed16e3a3fab9e8856d19ed5ba3b7a9
+  languageName: node
+  linkType: hard
+`), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("returns the resolved packages", func() {
+				entries, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(Equal([]yarn.LockfileEntry{
+					{
+						Name:        "@babel/code-frame",
+						Descriptors: []string{"@babel/code-frame@npm:^7.0.0", "@babel/code-frame@npm:^7.8.3"},
+						Version:     "7.8.3",
+						Resolution:  "@babel/code-frame@npm:7.8.3",
+						Checksum:    "05245d3b22a3ae4439e2b5ef2a8a2c73bd1f2a2f36bc3b2a3ba5cd2b6bbc8e8f",
+						Dependencies: map[string]string{
+							"@babel/highlight": "^7.8.3",
+						},
+						Line: 8,
+					},
+					{
+						Name:        "lodash",
+						Descriptors: []string{"lodash@npm:^4.17.15"},
+						Version:     "4.17.15",
+						Resolution:  "lodash@npm:4.17.15",
+						Checksum:    "3a25dc2df4e1b3bd00050eebd8ddd64bd8ed16e3a3fab9e8856d19ed5ba3b7a9",
+						Line:        17,
+					},
+				}))
+			})
+		})
+
+		context("when packages are installed under an alias", func() {
+			it.Before(func() {
+				err := ioutil.WriteFile(path, []byte(`# yarn lockfile v1
+
+
+"string-width-cjs@npm:string-width@^4.2.0":
+  version "4.2.3"
+  resolved "https://registry.yarnpkg.com/string-width/-/string-width-4.2.3.tgz#269c7117d27b05ad2e536830a8ec895ef9c6d010"
+  integrity sha512-wKyQRQpjJ0sIp62ErSZdGsjMJWsap5oRNihHhu6G7JVO/9jIB6UyevL+tXuOqrng8j/cxKTWyWUwvSTriiZz/g==
+`), 0644)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("names the resolved package and keeps the alias", func() {
+				entries, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(Equal([]yarn.LockfileEntry{
+					{
+						Name:        "string-width",
+						Alias:       "string-width-cjs",
+						Descriptors: []string{"string-width-cjs@npm:string-width@^4.2.0"},
+						Version:     "4.2.3",
+						Resolved:    "https://registry.yarnpkg.com/string-width/-/string-width-4.2.3.tgz#269c7117d27b05ad2e536830a8ec895ef9c6d010",
+						Integrity:   "sha512-wKyQRQpjJ0sIp62ErSZdGsjMJWsap5oRNihHhu6G7JVO/9jIB6UyevL+tXuOqrng8j/cxKTWyWUwvSTriiZz/g==",
+						Line:        4,
+					},
+				}))
+			})
+
+			context("when the yarn.lock was written by yarn v2+", func() {
+				it.Before(func() {
+					err := ioutil.WriteFile(path, []byte(`__metadata:
+  version: 6
+  cacheKey: 8
+
+"string-width-cjs@npm:string-width@^4.2.0":
+  version: 4.2.3
+  resolution: "string-width@npm:4.2.3"
+  checksum: e52c10dc3fbfcd6c3a15f159f54a90024241d0f149cf8aed2982a2d801d2e64df0bf1dc351cf8e95c3319323f9f220c16e740b06faecd53e2462df1d2b5443fb
+  languageName: node
+  linkType: hard
+`), 0644)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				it("names the resolved package and keeps the alias", func() {
+					entries, err := parser.Parse(path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(entries).To(Equal([]yarn.LockfileEntry{
+						{
+							Name:        "string-width",
+							Alias:       "string-width-cjs",
+							Descriptors: []string{"string-width-cjs@npm:string-width@^4.2.0"},
+							Version:     "4.2.3",
+							Resolution:  "string-width@npm:4.2.3",
+							Checksum:    "e52c10dc3fbfcd6c3a15f159f54a90024241d0f149cf8aed2982a2d801d2e64df0bf1dc351cf8e95c3319323f9f220c16e740b06faecd53e2462df1d2b5443fb",
+							Line:        5,
+						},
+					}))
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the yarn.lock file does not exist", func() {
+				it.Before(func() {
+					Expect(os.Remove(path)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+				})
+			})
+
+			context("when a yarn v2+ yarn.lock is malformed", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(path, []byte("__metadata:\n  version: 4\n%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse yarn.lock: yaml:")))
+				})
+			})
+
+			context("when a package descriptor is malformed", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(path, []byte("lodash:\n  version \"4.17.15\"\n"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(`failed to parse yarn.lock: invalid package descriptor "lodash"`))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,257 @@
+package yarn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:generate faux --interface RegistryValidator --output fakes/registry_validator.go
+type RegistryValidator interface {
+	Check(workingDir string, entries []LockfileEntry, allowed []string) ([]RegistryViolation, error)
+}
+
+//go:generate faux --interface PackageVerifier --output fakes/package_verifier.go
+type PackageVerifier interface {
+	Verify(workingDir string, entries []LockfileEntry) ([]IntegrityMismatch, error)
+}
+
+//go:generate faux --interface LicenseChecker --output fakes/license_checker.go
+type LicenseChecker interface {
+	Scan(workingDir string, entries []LockfileEntry, policy LicensePolicy) (LicenseReport, error)
+}
+
+//go:generate faux --interface VulnerabilityAuditor --output fakes/vulnerability_auditor.go
+type VulnerabilityAuditor interface {
+	Audit(databasePath string, entries []LockfileEntry) (AuditReport, error)
+}
+
+// LockfilePolicy runs the supply-chain checks that apply to the packages
+// listed in yarn.lock: the registry allowlist, the package archive integrity
+// check, the license policy and the vulnerability audit.
+type LockfilePolicy struct {
+	registryValidator    RegistryValidator
+	packageVerifier      PackageVerifier
+	licenseChecker       LicenseChecker
+	vulnerabilityAuditor VulnerabilityAuditor
+	logEmitter           LogEmitter
+}
+
+func NewLockfilePolicy(registryValidator RegistryValidator, packageVerifier PackageVerifier, licenseChecker LicenseChecker, vulnerabilityAuditor VulnerabilityAuditor, logEmitter LogEmitter) LockfilePolicy {
+	return LockfilePolicy{
+		registryValidator:    registryValidator,
+		packageVerifier:      packageVerifier,
+		licenseChecker:       licenseChecker,
+		vulnerabilityAuditor: vulnerabilityAuditor,
+		logEmitter:           logEmitter,
+	}
+}
+
+// Enforce returns an error when the lockfile entries break any of the
+// configured policies. Settings from the environment take precedence over
+// the buildpack.yml config. The registry and integrity checks are skipped
+// and the audit is skipped with a warning when there are no lockfile entries.
+// The license policy is skipped with a warning when the application ships
+// no packages to read licenses from.
+func (p LockfilePolicy) Enforce(workingDir string, entries []LockfileEntry, config Config) error {
+	if len(entries) > 0 {
+		err := p.checkRegistries(workingDir, entries, config.Registries)
+		if err != nil {
+			return err
+		}
+
+		err = p.verifyPackages(workingDir, entries)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := p.checkLicenses(workingDir, entries, config.Licenses)
+	if err != nil {
+		return err
+	}
+
+	return p.audit(workingDir, entries, config.Audit)
+}
+
+func (p LockfilePolicy) checkRegistries(workingDir string, entries []LockfileEntry, registries []string) error {
+	if value, ok := os.LookupEnv("BP_YARN_ALLOWED_REGISTRIES"); ok {
+		registries = splitList(value)
+	}
+
+	if len(registries) == 0 {
+		return nil
+	}
+
+	violations, err := p.registryValidator.Check(workingDir, entries, registries)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		var packages []string
+		for _, v := range violations {
+			packages = append(packages, v.String())
+		}
+
+		return fmt.Errorf("yarn.lock resolves packages from registries that are not allowed (%s):\n  %s", strings.Join(registries, ", "), strings.Join(packages, "\n  "))
+	}
+
+	return nil
+}
+
+func (p LockfilePolicy) verifyPackages(workingDir string, entries []LockfileEntry) error {
+	mismatches, err := p.packageVerifier.Verify(workingDir, entries)
+	if err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		var packages []string
+		for _, m := range mismatches {
+			packages = append(packages, m.String())
+		}
+
+		return fmt.Errorf("supply-chain check failed: package archives do not match yarn.lock:\n  %s", strings.Join(packages, "\n  "))
+	}
+
+	return nil
+}
+
+func (p LockfilePolicy) checkLicenses(workingDir string, entries []LockfileEntry, policy LicensePolicy) error {
+	policy = licensePolicyFromEnvironment(policy)
+	if !policy.Enabled() {
+		return nil
+	}
+
+	report, err := p.licenseChecker.Scan(workingDir, entries, policy)
+	if err != nil {
+		return err
+	}
+
+	if report.Source == "" {
+		p.logEmitter.Warning("license policy not enforced: there is no node_modules directory, yarn cache or offline mirror to read package licenses from")
+		return nil
+	}
+
+	p.logEmitter.LicenseReport(report)
+
+	if len(report.Violations) > 0 {
+		var violations []string
+		for _, v := range report.Violations {
+			violations = append(violations, fmt.Sprintf("%s@%s (%s)", v.Name, v.Version, v.License))
+		}
+
+		return fmt.Errorf("license policy violated by production packages: %s", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+func (p LockfilePolicy) audit(workingDir string, entries []LockfileEntry, policy AuditPolicy) error {
+	policy, err := auditPolicyFromEnvironment(workingDir, policy)
+	if err != nil {
+		return err
+	}
+
+	if policy.Advisories == "" {
+		return nil
+	}
+
+	var threshold Severity
+	if policy.Threshold != "" {
+		threshold, err = ParseSeverity(policy.Threshold)
+		if err != nil {
+			return fmt.Errorf("invalid audit threshold: %w", err)
+		}
+	}
+
+	if len(entries) == 0 {
+		p.logEmitter.Warning("skipping vulnerability audit: there are no yarn.lock packages to audit")
+		return nil
+	}
+
+	report, err := p.vulnerabilityAuditor.Audit(policy.Advisories, entries)
+	if err != nil {
+		return err
+	}
+
+	p.logEmitter.AuditReport(report)
+
+	if len(report.Findings) > 0 && threshold == "" {
+		p.logEmitter.Warning("%d known vulnerabilities found, set an audit threshold to fail the build", len(report.Findings))
+	}
+
+	if threshold != "" {
+		var findings []string
+		for _, f := range report.Findings {
+			if f.Severity.AtLeast(threshold) {
+				findings = append(findings, fmt.Sprintf("%s@%s (%s)", f.Name, f.Version, f.ID))
+			}
+		}
+
+		if len(findings) > 0 {
+			return fmt.Errorf("found vulnerabilities at or above %s severity: %s", threshold, strings.Join(findings, ", "))
+		}
+	}
+
+	return nil
+}
+
+// licensePolicyFromEnvironment replaces the buildpack.yml license rules with
+// the comma separated lists in BP_YARN_LICENSE_ALLOW and BP_YARN_LICENSE_DENY
+// when they are set.
+func licensePolicyFromEnvironment(policy LicensePolicy) LicensePolicy {
+	if value, ok := os.LookupEnv("BP_YARN_LICENSE_ALLOW"); ok {
+		policy.Allow = splitList(value)
+	}
+
+	if value, ok := os.LookupEnv("BP_YARN_LICENSE_DENY"); ok {
+		policy.Deny = splitList(value)
+	}
+
+	return policy
+}
+
+// auditPolicyFromEnvironment resolves where the advisory database lives and
+// which severity fails the build. BP_YARN_ADVISORY_DB and
+// BP_YARN_AUDIT_THRESHOLD take precedence over buildpack.yml, and a service
+// binding of type osv-advisories is used when no path has been set. Relative
+// paths are resolved against the working directory.
+func auditPolicyFromEnvironment(workingDir string, policy AuditPolicy) (AuditPolicy, error) {
+	if value, ok := os.LookupEnv("BP_YARN_ADVISORY_DB"); ok {
+		policy.Advisories = value
+	}
+
+	if value, ok := os.LookupEnv("BP_YARN_AUDIT_THRESHOLD"); ok {
+		policy.Threshold = value
+	}
+
+	if policy.Advisories == "" {
+		binding, err := findAdvisoryBinding(ServiceBindingRoot())
+		if err != nil {
+			return AuditPolicy{}, fmt.Errorf("failed to read service bindings: %w", err)
+		}
+
+		policy.Advisories = binding
+	}
+
+	if policy.Advisories != "" && !filepath.IsAbs(policy.Advisories) {
+		policy.Advisories = filepath.Join(workingDir, policy.Advisories)
+	}
+
+	return policy, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,413 @@
+package yarn_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/ForestEckhardt/yarn-cnb/yarn/fakes"
+	"github.com/cloudfoundry/packit/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLockfilePolicy(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		entries    []yarn.LockfileEntry
+		config     yarn.Config
+
+		registryValidator *fakes.RegistryValidator
+		packageVerifier   *fakes.PackageVerifier
+		licenseChecker    *fakes.LicenseChecker
+		auditor           *fakes.VulnerabilityAuditor
+		buffer            *bytes.Buffer
+
+		policy yarn.LockfilePolicy
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		entries = []yarn.LockfileEntry{
+			{
+				Name:      "some-package",
+				Version:   "1.2.3",
+				Resolved:  "some-resolved-url",
+				Integrity: "some-integrity",
+			},
+		}
+
+		config = yarn.Config{}
+
+		registryValidator = &fakes.RegistryValidator{}
+		packageVerifier = &fakes.PackageVerifier{}
+		licenseChecker = &fakes.LicenseChecker{}
+		auditor = &fakes.VulnerabilityAuditor{}
+
+		buffer = bytes.NewBuffer(nil)
+
+		policy = yarn.NewLockfilePolicy(registryValidator, packageVerifier, licenseChecker, auditor, yarn.NewLogEmitter(scribe.NewLogger(buffer)))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("verifies the package archives and skips the unconfigured checks", func() {
+		err := policy.Enforce(workingDir, entries, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(packageVerifier.VerifyCall.Receives.WorkingDir).To(Equal(workingDir))
+		Expect(packageVerifier.VerifyCall.Receives.Entries).To(Equal(entries))
+
+		Expect(registryValidator.CheckCall.CallCount).To(Equal(0))
+		Expect(licenseChecker.ScanCall.CallCount).To(Equal(0))
+		Expect(auditor.AuditCall.CallCount).To(Equal(0))
+	})
+
+	context("when there are no lockfile entries", func() {
+		it.Before(func() {
+			config.Registries = []string{"npm.example.com"}
+		})
+
+		it("skips the registry and integrity checks", func() {
+			err := policy.Enforce(workingDir, nil, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(registryValidator.CheckCall.CallCount).To(Equal(0))
+			Expect(packageVerifier.VerifyCall.CallCount).To(Equal(0))
+		})
+	})
+
+	context("when a license policy is configured", func() {
+		it.Before(func() {
+			config.Licenses = yarn.LicensePolicy{
+				Allow: []string{"MIT"},
+			}
+
+			licenseChecker.ScanCall.Returns.LicenseReport = yarn.LicenseReport{
+				Source: "node_modules",
+				Licenses: map[string][]string{
+					"MIT": []string{"leftpad@1.3.0"},
+				},
+			}
+		})
+
+		it("scans the installed packages and prints the report", func() {
+			err := policy.Enforce(workingDir, entries, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(licenseChecker.ScanCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(licenseChecker.ScanCall.Receives.Entries).To(Equal(entries))
+			Expect(licenseChecker.ScanCall.Receives.Policy).To(Equal(yarn.LicensePolicy{
+				Allow: []string{"MIT"},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("License report"))
+			Expect(buffer.String()).To(ContainSubstring("MIT (1)"))
+		})
+
+		context("when the policy is overridden by the environment", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_LICENSE_ALLOW", "Apache-2.0, BSD-*")).To(Succeed())
+				Expect(os.Setenv("BP_YARN_LICENSE_DENY", "GPL-*")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_YARN_LICENSE_ALLOW")).To(Succeed())
+				Expect(os.Unsetenv("BP_YARN_LICENSE_DENY")).To(Succeed())
+			})
+
+			it("uses the environment policy", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(licenseChecker.ScanCall.Receives.Policy).To(Equal(yarn.LicensePolicy{
+					Allow: []string{"Apache-2.0", "BSD-*"},
+					Deny:  []string{"GPL-*"},
+				}))
+			})
+		})
+
+		context("when production packages violate the policy", func() {
+			it.Before(func() {
+				licenseChecker.ScanCall.Returns.LicenseReport.Violations = []yarn.LicenseViolation{
+					{Name: "copyleft", Version: "2.0.0", License: "GPL-3.0"},
+				}
+			})
+
+			it("returns an error listing the violations", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("license policy violated by production packages: copyleft@2.0.0 (GPL-3.0)"))
+
+				Expect(buffer.String()).To(ContainSubstring("Production packages violating the license policy:"))
+			})
+		})
+
+		context("when there are no packages to read licenses from", func() {
+			it.Before(func() {
+				licenseChecker.ScanCall.Returns.LicenseReport = yarn.LicenseReport{}
+			})
+
+			it("warns that the policy is not enforced", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(ContainSubstring("license policy not enforced: there is no node_modules directory, yarn cache or offline mirror to read package licenses from"))
+				Expect(buffer.String()).NotTo(ContainSubstring("License report"))
+			})
+		})
+	})
+
+	context("when an advisory database is configured", func() {
+		it.Before(func() {
+			config.Audit = yarn.AuditPolicy{
+				Advisories: "advisories",
+				Threshold:  "high",
+			}
+
+			auditor.AuditCall.Returns.AuditReport = yarn.AuditReport{
+				Packages: 2,
+				Findings: []yarn.AuditFinding{
+					{
+						ID:       "GHSA-aaaa",
+						Summary:  "Prototype pollution",
+						Severity: yarn.SeverityModerate,
+						Name:     "lodash",
+						Version:  "4.17.15",
+						Line:     5,
+					},
+				},
+			}
+		})
+
+		it("audits the lockfile packages and prints the report", func() {
+			err := policy.Enforce(workingDir, entries, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(auditor.AuditCall.Receives.DatabasePath).To(Equal(filepath.Join(workingDir, "advisories")))
+			Expect(auditor.AuditCall.Receives.Entries).To(Equal(entries))
+
+			Expect(buffer.String()).To(ContainSubstring("Vulnerability audit"))
+			Expect(buffer.String()).To(ContainSubstring("lodash@4.17.15 GHSA-aaaa: Prototype pollution (yarn.lock line 5)"))
+		})
+
+		context("when there are no lockfile entries", func() {
+			it("skips the audit with a warning", func() {
+				err := policy.Enforce(workingDir, nil, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(auditor.AuditCall.CallCount).To(Equal(0))
+				Expect(buffer.String()).To(ContainSubstring("Warning: skipping vulnerability audit: there are no yarn.lock packages to audit"))
+				Expect(buffer.String()).NotTo(ContainSubstring("No known vulnerabilities"))
+			})
+		})
+
+		context("when a finding is at or above the threshold", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_AUDIT_THRESHOLD", "moderate")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_YARN_AUDIT_THRESHOLD")).To(Succeed())
+			})
+
+			it("returns an error listing the findings", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("found vulnerabilities at or above moderate severity: lodash@4.17.15 (GHSA-aaaa)"))
+			})
+		})
+
+		context("when the database path is set in the environment", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_ADVISORY_DB", "/some/advisories")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_YARN_ADVISORY_DB")).To(Succeed())
+			})
+
+			it("uses the environment path", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(auditor.AuditCall.Receives.DatabasePath).To(Equal("/some/advisories"))
+			})
+		})
+
+		context("when the database is provided by a service binding", func() {
+			var bindingsDir string
+
+			it.Before(func() {
+				config.Audit.Advisories = ""
+
+				var err error
+				bindingsDir, err = ioutil.TempDir("", "bindings")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.MkdirAll(filepath.Join(bindingsDir, "other"), os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(bindingsDir, "other", "type"), []byte("other"), 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(bindingsDir, "osv"), os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(bindingsDir, "osv", "type"), []byte("osv-advisories\n"), 0644)).To(Succeed())
+
+				Expect(os.Setenv("SERVICE_BINDING_ROOT", bindingsDir)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("SERVICE_BINDING_ROOT")).To(Succeed())
+				Expect(os.RemoveAll(bindingsDir)).To(Succeed())
+			})
+
+			it("uses the binding", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(auditor.AuditCall.Receives.DatabasePath).To(Equal(filepath.Join(bindingsDir, "osv")))
+			})
+		})
+	})
+
+	context("when a registry allowlist is configured", func() {
+		it.Before(func() {
+			config.Registries = []string{"npm.example.com"}
+		})
+
+		it("checks the lockfile packages against it", func() {
+			err := policy.Enforce(workingDir, entries, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(registryValidator.CheckCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(registryValidator.CheckCall.Receives.Entries).To(Equal(entries))
+			Expect(registryValidator.CheckCall.Receives.Allowed).To(Equal([]string{"npm.example.com"}))
+		})
+
+		context("when the allowlist is set in the environment", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_YARN_ALLOWED_REGISTRIES", "npm.example.com,*.mirror.example.com")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_YARN_ALLOWED_REGISTRIES")).To(Succeed())
+			})
+
+			it("uses the environment allowlist", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(registryValidator.CheckCall.Receives.Allowed).To(Equal([]string{"npm.example.com", "*.mirror.example.com"}))
+			})
+		})
+
+		context("when packages are resolved from other registries", func() {
+			it.Before(func() {
+				registryValidator.CheckCall.Returns.RegistryViolationSlice = []yarn.RegistryViolation{
+					{
+						Name:    "lodash",
+						Version: "4.17.15",
+						Source:  "https://registry.npmjs.org/lodash/-/lodash-4.17.15.tgz",
+						Host:    "registry.npmjs.org",
+						Line:    12,
+					},
+				}
+			})
+
+			it("returns an error listing each package with its lockfile line", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("yarn.lock resolves packages from registries that are not allowed (npm.example.com):\n  yarn.lock line 12: lodash@4.17.15 from registry.npmjs.org (https://registry.npmjs.org/lodash/-/lodash-4.17.15.tgz)"))
+				Expect(packageVerifier.VerifyCall.CallCount).To(Equal(0))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the registries cannot be checked", func() {
+			it.Before(func() {
+				config.Registries = []string{"npm.example.com"}
+				registryValidator.CheckCall.Returns.Error = errors.New("failed to check registries")
+			})
+
+			it("returns an error", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("failed to check registries"))
+			})
+		})
+
+		context("when the package archives cannot be verified", func() {
+			it.Before(func() {
+				packageVerifier.VerifyCall.Returns.Error = errors.New("failed to verify package archives")
+			})
+
+			it("returns an error", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("failed to verify package archives"))
+			})
+		})
+
+		context("when a package archive does not match yarn.lock", func() {
+			it.Before(func() {
+				packageVerifier.VerifyCall.Returns.IntegrityMismatchSlice = []yarn.IntegrityMismatch{
+					{
+						Name:     "lodash",
+						Version:  "4.17.15",
+						Path:     "mirror/lodash-4.17.15.tgz",
+						Expected: "sha1-expected",
+						Actual:   "sha1-actual",
+					},
+				}
+			})
+
+			it("returns a supply-chain error naming the package", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("supply-chain check failed: package archives do not match yarn.lock:\n  lodash@4.17.15: mirror/lodash-4.17.15.tgz has sha1-actual, yarn.lock expects sha1-expected"))
+			})
+		})
+
+		context("when the licenses cannot be scanned", func() {
+			it.Before(func() {
+				config.Licenses.Deny = []string{"GPL-*"}
+				licenseChecker.ScanCall.Returns.Error = errors.New("failed to scan licenses")
+			})
+
+			it("returns an error", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("failed to scan licenses"))
+			})
+		})
+
+		context("when the audit threshold is invalid", func() {
+			it.Before(func() {
+				config.Audit = yarn.AuditPolicy{
+					Advisories: "advisories",
+					Threshold:  "severe",
+				}
+			})
+
+			it("returns an error", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError(ContainSubstring("invalid audit threshold: unknown severity \"severe\"")))
+			})
+		})
+
+		context("when the packages cannot be audited", func() {
+			it.Before(func() {
+				config.Audit.Advisories = "advisories"
+				auditor.AuditCall.Returns.Error = errors.New("failed to audit packages")
+			})
+
+			it("returns an error", func() {
+				err := policy.Enforce(workingDir, entries, config)
+				Expect(err).To(MatchError("failed to audit packages"))
+			})
+		})
+	})
+}
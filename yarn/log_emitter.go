@@ -1,29 +1,215 @@
 package yarn
 
 import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/postal"
 	"github.com/cloudfoundry/packit/scribe"
 )
 
+// DebugLogLevel is the value of BP_LOG_LEVEL that prints the internals of the
+// build along with the normal output.
+const DebugLogLevel = "debug"
+
 type LogEmitter struct {
 	scribe.Logger
+
+	report *BuildReport
+	debug  bool
 }
 
 func NewLogEmitter(logger scribe.Logger) LogEmitter {
-	return LogEmitter{logger}
+	return LogEmitter{
+		Logger: logger,
+		report: newBuildReport(),
+	}
+}
+
+// WithLevel returns an emitter that prints debug output when level is
+// DebugLogLevel.
+func (e LogEmitter) WithLevel(level string) LogEmitter {
+	e.debug = strings.EqualFold(strings.TrimSpace(level), DebugLogLevel)
+	return e
+}
+
+// Debug prints a message only when the debug log level is enabled.
+func (e LogEmitter) Debug(format string, v ...interface{}) {
+	if !e.debug {
+		return
+	}
+
+	e.Logger.Subprocess("[debug] "+format, v...)
+}
+
+func (e LogEmitter) DebugPlanEntries(entries []packit.BuildpackPlanEntry) {
+	e.Debug("Buildpack plan entries (%d):", len(entries))
+	for _, entry := range entries {
+		e.Debug("  %s %q %s", entry.Name, entry.Version, formatMetadata(entry.Metadata))
+	}
+}
+
+func (e LogEmitter) DebugCandidates(name, constraint, stack string, candidates []postal.Dependency) {
+	e.Debug("Candidate %s versions for %q on %s (%d):", name, constraint, stack, len(candidates))
+	for _, candidate := range candidates {
+		e.Debug("  %s (sha256: %s)", candidate.Version, candidate.SHA256)
+	}
+}
+
+func (e LogEmitter) DebugCacheKey(key CacheKey) {
+	e.Debug("Cache key: %s=%s %s=%s %s=%s",
+		metadataKeyDependencySHA, key.SHA,
+		metadataKeyBuildpackVersion, key.BuildpackVersion,
+		metadataKeyStack, key.Stack)
+}
+
+// DebugEnvironment prints the environment a layer provides to the processes
+// that run after it, such as yarn.
+func (e LogEmitter) DebugEnvironment(layer string, env packit.Environment) {
+	e.Debug("Environment from layer %s:", layer)
+
+	var keys []string
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		e.Debug("  %s=%s", key, env[key])
+	}
+}
+
+func (e LogEmitter) DebugCommand(dir, name string, args ...string) {
+	e.Debug("Running '%s' in %s", strings.Join(append([]string{name}, args...), " "), dir)
 }
 
 func (e LogEmitter) BuildpackTitle(name, version string) {
 	e.Logger.Title("%s %s", name, version)
 }
 
-func (e LogEmitter) CompletionTime(then time.Time) {
-	e.Logger.Action("Completed in %s", time.Since(then).Round(time.Millisecond))
+func (e LogEmitter) CompletionTime(phase string, duration time.Duration) {
+	e.report.addTiming(phase, duration)
+
+	e.Logger.Action("Completed in %s", duration.Round(time.Millisecond))
 	e.Logger.Break()
 }
 
+// PhaseTime records the duration of a build phase for the timing summary
+// without printing it.
+func (e LogEmitter) PhaseTime(phase string, duration time.Duration) {
+	e.report.addTiming(phase, duration)
+}
+
+// PhaseTimings returns the duration in milliseconds of every phase recorded
+// so far.
+func (e LogEmitter) PhaseTimings() map[string]int64 {
+	timings := map[string]int64{}
+	for _, timing := range e.report.Timings {
+		timings[timing.Phase] += timing.DurationMS
+	}
+
+	return timings
+}
+
+func (e LogEmitter) PhaseSummary() {
+	e.Logger.Process("Build timings")
+
+	width := 0
+	for _, timing := range e.report.Timings {
+		if len(timing.Phase) > width {
+			width = len(timing.Phase)
+		}
+	}
+
+	for _, timing := range e.report.Timings {
+		duration := time.Duration(timing.DurationMS) * time.Millisecond
+		e.Logger.Subprocess("%-*s  %s", width, timing.Phase, duration)
+	}
+
+	e.Logger.Break()
+}
+
+// ResolvedDependency prints the dependency chosen by the resolver, how it was
+// chosen and which other versions could have been used on this stack.
+func (e LogEmitter) ResolvedDependency(dependency postal.Dependency, constraint, source string, candidates []postal.Dependency) {
+	e.Logger.Process("Resolving %s version", dependency.Name)
+	e.Logger.Subprocess("Selected %s version (using %s): %s", dependency.Name, source, dependency.Version)
+	e.Logger.Action(scribe.FormattedMap{
+		"version source": source,
+		"constraint":     constraint,
+		"sha256":         dependency.SHA256,
+		"stacks":         strings.Join(dependency.Stacks, ", "),
+	}.String())
+	e.Logger.Break()
+
+	var others scribe.FormattedList
+	for _, candidate := range candidates {
+		if candidate.Version != dependency.Version {
+			others = append(others, candidate.Version)
+		}
+	}
+
+	if len(others) == 0 {
+		e.Logger.Subprocess("No other versions available")
+	} else {
+		e.Logger.Subprocess("Other versions available:")
+		e.Logger.Action(others.String())
+	}
+	e.Logger.Break()
+}
+
+// SelectedDependency records the version of a dependency used by the build
+// and where the requested version came from.
+func (e LogEmitter) SelectedDependency(name, version, requested, source string) {
+	e.report.Dependencies = append(e.report.Dependencies, ReportDependency{
+		Name:      name,
+		Version:   version,
+		Requested: requested,
+		Source:    source,
+	})
+}
+
+func (e LogEmitter) Warning(format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	e.report.Warnings = append(e.report.Warnings, message)
+
+	e.Logger.Subprocess("Warning: %s", message)
+}
+
+// DeprecatedBuildpackYML warns that buildpack.yml is deprecated and lists the
+// environment variables that replace the options it sets.
+func (e LogEmitter) DeprecatedBuildpackYML(variables []string) {
+	e.Warning("buildpack.yml is deprecated and will be removed in a future version, set these environment variables instead:")
+	for _, variable := range variables {
+		e.Logger.Action(variable)
+	}
+	e.Logger.Break()
+}
+
+func (e LogEmitter) CacheInvalidated(mismatch CacheMismatch) {
+	e.report.Cache = ReportCache{Layer: PlanDependencyYarn, Reason: mismatch.String()}
+
+	if mismatch.Reason == CacheMismatchNoLayer {
+		e.Logger.Subprocess("No cached layer")
+		return
+	}
+
+	e.Logger.Subprocess("Cached layer invalid: %s", mismatch)
+}
+
+func (e LogEmitter) LayerContentsInvalid(err error) {
+	e.report.Cache = ReportCache{Layer: PlanDependencyYarn, Reason: err.Error()}
+
+	e.Logger.Subprocess("Cached layer invalid: %s", err)
+}
+
 func (e LogEmitter) ReusingLayer(layerPath string) {
+	e.report.Cache = ReportCache{Layer: filepath.Base(layerPath), Hit: true}
+
 	e.Logger.Process("Reusing cached layer %s", layerPath)
 	e.Logger.Break()
 }
@@ -45,3 +231,81 @@ func (e LogEmitter) FoundYarnLock(found bool) {
 	e.Logger.Action("yarn.lock -> %s", foundMessage)
 	e.Logger.Break()
 }
+
+func (e LogEmitter) LicenseReport(report LicenseReport) {
+	e.Logger.Process("License report")
+	if report.Source != "" {
+		e.Logger.Subprocess("Licenses read from %s", report.Source)
+	}
+
+	var licenses []string
+	for license := range report.Licenses {
+		licenses = append(licenses, license)
+	}
+	sort.Strings(licenses)
+
+	for _, license := range licenses {
+		packages := report.Licenses[license]
+		e.Logger.Subprocess("%s (%d)", license, len(packages))
+		for _, p := range packages {
+			e.Logger.Action(p)
+		}
+	}
+
+	if len(report.Violations) > 0 {
+		e.Logger.Break()
+		e.Logger.Subprocess("Production packages violating the license policy:")
+		for _, v := range report.Violations {
+			e.Logger.Action("%s@%s (%s)", v.Name, v.Version, v.License)
+		}
+	}
+
+	e.Logger.Break()
+}
+
+func (e LogEmitter) AuditReport(report AuditReport) {
+	e.Logger.Process("Vulnerability audit")
+
+	if len(report.Findings) == 0 {
+		e.Logger.Subprocess("No known vulnerabilities in %d packages", report.Packages)
+		e.Logger.Break()
+		return
+	}
+
+	e.Logger.Subprocess("Found %d vulnerabilities in %d packages", len(report.Findings), report.Packages)
+	for _, severity := range Severities {
+		var findings []AuditFinding
+		for _, f := range report.Findings {
+			if f.Severity == severity {
+				findings = append(findings, f)
+			}
+		}
+
+		if len(findings) == 0 {
+			continue
+		}
+
+		e.Logger.Break()
+		e.Logger.Subprocess("%s (%d)", severity, len(findings))
+		for _, f := range findings {
+			e.Logger.Action("%s@%s %s: %s (yarn.lock line %d)", f.Name, f.Version, f.ID, f.Summary, f.Line)
+		}
+	}
+
+	e.Logger.Break()
+}
+
+func formatMetadata(metadata map[string]interface{}) string {
+	var keys []string
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, metadata[key]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -2,10 +2,13 @@ package yarn_test
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/cloudfoundry/packit"
+	"github.com/cloudfoundry/packit/postal"
 	"github.com/cloudfoundry/packit/scribe"
 	"github.com/sclevine/spec"
 
@@ -33,11 +36,193 @@ func testLogEmitter(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("CompletionTime", func() {
-		it("returns a string that prints out the time elapsed since the passed in value round to the millisecond", func() {
-			then := time.Now()
-			time.Sleep(100 * time.Millisecond)
-			emitter.CompletionTime(then)
-			Expect(buffer.String()).To(MatchRegexp(`      Completed in (\d+\.\d+|\d{3})\w+\n\n`))
+		it("prints the duration of the phase rounded to the millisecond", func() {
+			emitter.CompletionTime("some-phase", 1234567890*time.Nanosecond)
+			Expect(buffer.String()).To(Equal("      Completed in 1.235s\n\n"))
+		})
+	})
+
+	context("PhaseSummary", func() {
+		it("prints a table of the recorded phase timings", func() {
+			emitter.PhaseTime("resolve", 12*time.Millisecond)
+			emitter.CompletionTime("download and extract", 1500*time.Millisecond)
+			buffer.Reset()
+
+			emitter.PhaseSummary()
+			Expect(buffer.String()).To(Equal(`  Build timings
+    resolve               12ms
+    download and extract  1.5s
+
+`))
+			Expect(emitter.PhaseTimings()).To(Equal(map[string]int64{
+				"resolve":              12,
+				"download and extract": 1500,
+			}))
+		})
+	})
+
+	context("Warning", func() {
+		it("prints the warning", func() {
+			emitter.Warning("%d things went wrong", 2)
+			Expect(buffer.String()).To(Equal("    Warning: 2 things went wrong\n"))
+		})
+	})
+
+	context("DeprecatedBuildpackYML", func() {
+		it("prints a warning listing the replacement environment variables", func() {
+			emitter.DeprecatedBuildpackYML([]string{`BP_YARN_VERSION="1.x"`, `BP_YARN_AUDIT_THRESHOLD="high"`})
+			Expect(buffer.String()).To(Equal(`    Warning: buildpack.yml is deprecated and will be removed in a future version, set these environment variables instead:
+      BP_YARN_VERSION="1.x"
+      BP_YARN_AUDIT_THRESHOLD="high"
+
+`))
+		})
+	})
+
+	context("CacheInvalidated", func() {
+		it("prints the reason the cached layer could not be reused", func() {
+			emitter.CacheInvalidated(yarn.CacheMismatch{
+				Reason: yarn.CacheMismatchStackChanged,
+				Key:    "stack",
+				Old:    "some-stack",
+				New:    "other-stack",
+			})
+			Expect(buffer.String()).To(Equal("    Cached layer invalid: stack changed (some-stack -> other-stack)\n"))
+		})
+
+		context("when there is no cached layer", func() {
+			it("says so instead of calling the layer invalid", func() {
+				emitter.CacheInvalidated(yarn.CacheMismatch{Reason: yarn.CacheMismatchNoLayer})
+				Expect(buffer.String()).To(Equal("    No cached layer\n"))
+			})
+		})
+	})
+
+	context("LayerContentsInvalid", func() {
+		it("prints the reason the cached layer contents could not be reused", func() {
+			emitter.LayerContentsInvalid(errors.New("missing bin/yarn"))
+			Expect(buffer.String()).To(Equal("    Cached layer invalid: missing bin/yarn\n"))
+		})
+	})
+
+	context("LicenseReport", func() {
+		it("prints the installed packages grouped by license and any violations", func() {
+			emitter.LicenseReport(yarn.LicenseReport{
+				Source: ".yarn/cache",
+				Licenses: map[string][]string{
+					"MIT":     []string{"leftpad@1.3.0", "lodash@4.17.15"},
+					"GPL-3.0": []string{"copyleft@2.0.0"},
+				},
+				Violations: []yarn.LicenseViolation{
+					{Name: "copyleft", Version: "2.0.0", License: "GPL-3.0"},
+				},
+			})
+			Expect(buffer.String()).To(Equal(`  License report
+    Licenses read from .yarn/cache
+    GPL-3.0 (1)
+      copyleft@2.0.0
+    MIT (2)
+      leftpad@1.3.0
+      lodash@4.17.15
+
+    Production packages violating the license policy:
+      copyleft@2.0.0 (GPL-3.0)
+
+`))
+		})
+	})
+
+	context("AuditReport", func() {
+		it("prints the findings grouped by severity", func() {
+			emitter.AuditReport(yarn.AuditReport{
+				Packages: 3,
+				Findings: []yarn.AuditFinding{
+					{ID: "GHSA-bbbb", Summary: "Code injection", Severity: yarn.SeverityCritical, Name: "evil", Version: "1.0.0", Line: 9},
+					{ID: "GHSA-aaaa", Summary: "Prototype pollution", Severity: yarn.SeverityModerate, Name: "lodash", Version: "4.17.15", Line: 5},
+				},
+			})
+			Expect(buffer.String()).To(Equal(`  Vulnerability audit
+    Found 2 vulnerabilities in 3 packages
+
+    critical (1)
+      evil@1.0.0 GHSA-bbbb: Code injection (yarn.lock line 9)
+
+    moderate (1)
+      lodash@4.17.15 GHSA-aaaa: Prototype pollution (yarn.lock line 5)
+
+`))
+		})
+
+		context("when there are no findings", func() {
+			it("prints the number of audited packages", func() {
+				emitter.AuditReport(yarn.AuditReport{Packages: 3})
+				Expect(buffer.String()).To(Equal("  Vulnerability audit\n    No known vulnerabilities in 3 packages\n\n"))
+			})
+		})
+	})
+
+	context("ResolvedDependency", func() {
+		it("prints the selected version and the other available versions", func() {
+			emitter.ResolvedDependency(postal.Dependency{
+				Name:    "Yarn",
+				SHA256:  "some-sha",
+				Stacks:  []string{"some-stack", "other-stack"},
+				Version: "1.22.0",
+			}, "*", "default", []postal.Dependency{
+				{Version: "1.22.0"},
+				{Version: "1.21.1"},
+				{Version: "1.21.0"},
+			})
+			Expect(buffer.String()).To(Equal(`  Resolving Yarn version
+    Selected Yarn version (using default): 1.22.0
+      constraint     -> *
+      sha256         -> some-sha
+      stacks         -> some-stack, other-stack
+      version source -> default
+
+    Other versions available:
+      1.21.0
+      1.21.1
+
+`))
+		})
+
+		context("when there are no other versions", func() {
+			it("says so", func() {
+				emitter.ResolvedDependency(postal.Dependency{Name: "Yarn", Version: "1.22.0"}, "*", "default", []postal.Dependency{
+					{Version: "1.22.0"},
+				})
+				Expect(buffer.String()).To(ContainSubstring("    No other versions available\n"))
+			})
+		})
+	})
+
+	context("Debug", func() {
+		it("prints nothing by default", func() {
+			emitter.Debug("some-message")
+			Expect(buffer.String()).To(BeEmpty())
+		})
+
+		context("when the log level is debug", func() {
+			it.Before(func() {
+				emitter = emitter.WithLevel("DEBUG")
+			})
+
+			it("prints the message", func() {
+				emitter.Debug("some-message %d", 1)
+				Expect(buffer.String()).To(Equal("    [debug] some-message 1\n"))
+			})
+
+			it("prints the layer environment sorted by key", func() {
+				emitter.DebugEnvironment("yarn", packit.Environment{
+					"PATH.delim":  ":",
+					"PATH.append": "/layers/yarn",
+				})
+				Expect(buffer.String()).To(Equal(`    [debug] Environment from layer yarn:
+    [debug]   PATH.append=/layers/yarn
+    [debug]   PATH.delim=:
+`))
+			})
 		})
 	})
 
@@ -0,0 +1,226 @@
+package yarn
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type IntegrityMismatch struct {
+	Name     string
+	Version  string
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (m IntegrityMismatch) String() string {
+	return fmt.Sprintf("%s@%s: %s has %s, yarn.lock expects %s", m.Name, m.Version, m.Path, m.Actual, m.Expected)
+}
+
+// MirrorVerifier checks the package archives shipped with the application
+// against the checksums recorded in yarn.lock. Yarn v1 archives are read from
+// the yarn-offline-mirror directory configured in .yarnrc, while yarn v2+
+// (Berry) archives are read from the cache folder, which defaults to
+// .yarn/cache. Archives in the global cache are not part of the application
+// and are not checked.
+type MirrorVerifier struct{}
+
+func NewMirrorVerifier() MirrorVerifier {
+	return MirrorVerifier{}
+}
+
+// Verify returns every lockfile entry whose archive in the offline mirror or
+// cache does not match its recorded checksum. Entries without an archive or
+// without a checksum are skipped, since yarn verifies them when fetching.
+func (v MirrorVerifier) Verify(workingDir string, entries []LockfileEntry) ([]IntegrityMismatch, error) {
+	config, err := NewYarnrcParser().Parse(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify package archives: %w", err)
+	}
+
+	mirrorDir := config.Classic.OfflineMirror
+	cacheDir := config.Berry.ProjectCache()
+
+	var mismatches []IntegrityMismatch
+	for _, entry := range entries {
+		var (
+			mismatch IntegrityMismatch
+			found    bool
+		)
+
+		if entry.Checksum != "" && cacheDir != "" {
+			mismatch, found, err = verifyBerryArchive(cacheDir, entry)
+		} else if mirrorDir != "" {
+			mismatch, found, err = verifyMirrorTarball(mirrorDir, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify package archives: %w", err)
+		}
+
+		if found {
+			if rel, err := filepath.Rel(workingDir, mismatch.Path); err == nil && !strings.HasPrefix(rel, "..") {
+				mismatch.Path = rel
+			}
+
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	return mismatches, nil
+}
+
+// verifyMirrorTarball compares a yarn v1 offline mirror tarball against the
+// strongest hash in the entry integrity, falling back to the SHA-1 in the
+// fragment of the resolved URL.
+func verifyMirrorTarball(mirrorDir string, entry LockfileEntry) (IntegrityMismatch, bool, error) {
+	resolved, err := url.Parse(entry.Resolved)
+	if err != nil || resolved.Path == "" {
+		return IntegrityMismatch{}, false, nil
+	}
+
+	tarball := mirrorTarball(mirrorDir, entry)
+	_, err = os.Stat(tarball)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return IntegrityMismatch{}, false, nil
+		}
+
+		return IntegrityMismatch{}, false, err
+	}
+
+	var algorithm, expected string
+	for _, field := range strings.Fields(entry.Integrity) {
+		parts := strings.SplitN(field, "-", 2)
+		if len(parts) != 2 || (parts[0] != "sha512" && parts[0] != "sha1") {
+			continue
+		}
+
+		if algorithm != "sha512" {
+			algorithm, expected = parts[0], parts[1]
+		}
+	}
+
+	encode := base64.StdEncoding.EncodeToString
+	if algorithm == "" {
+		if resolved.Fragment == "" {
+			return IntegrityMismatch{}, false, nil
+		}
+
+		algorithm, expected, encode = "sha1", resolved.Fragment, hex.EncodeToString
+	}
+
+	newHash := sha512.New
+	if algorithm == "sha1" {
+		newHash = sha1.New
+	}
+
+	sum, err := sumFile(tarball, newHash())
+	if err != nil {
+		return IntegrityMismatch{}, false, err
+	}
+
+	actual := encode(sum)
+	if actual == expected {
+		return IntegrityMismatch{}, false, nil
+	}
+
+	return IntegrityMismatch{
+		Name:     entry.Name,
+		Version:  entry.Version,
+		Path:     tarball,
+		Expected: algorithm + "-" + expected,
+		Actual:   algorithm + "-" + actual,
+	}, true, nil
+}
+
+// verifyBerryArchive compares the yarn v2+ cache archives of an entry against
+// its checksum, the hex SHA-512 of the archive optionally prefixed with the
+// cache key, as in 10c0/<hex>. Archives are named after the resolved package,
+// so aliased packages are found under the name they point to.
+func verifyBerryArchive(cacheDir string, entry LockfileEntry) (IntegrityMismatch, bool, error) {
+	archives, err := berryArchives(cacheDir, entry)
+	if err != nil || len(archives) == 0 {
+		return IntegrityMismatch{}, false, err
+	}
+
+	expected := entry.Checksum
+	if index := strings.LastIndex(expected, "/"); index >= 0 {
+		expected = expected[index+1:]
+	}
+
+	var actual string
+	for _, archive := range archives {
+		sum, err := sumFile(archive, sha512.New())
+		if err != nil {
+			return IntegrityMismatch{}, false, err
+		}
+
+		actual = hex.EncodeToString(sum)
+		if actual == expected {
+			return IntegrityMismatch{}, false, nil
+		}
+	}
+
+	return IntegrityMismatch{
+		Name:     entry.Name,
+		Version:  entry.Version,
+		Path:     archives[len(archives)-1],
+		Expected: "sha512-" + expected,
+		Actual:   "sha512-" + actual,
+	}, true, nil
+}
+
+// mirrorTarball returns where the yarn v1 offline mirror keeps the tarball of
+// an entry. Yarn names it after the resolved URL, prefixed with the scope of
+// scoped packages.
+func mirrorTarball(mirrorDir string, entry LockfileEntry) string {
+	resolved, err := url.Parse(entry.Resolved)
+	if err != nil || resolved.Path == "" {
+		return ""
+	}
+
+	filename := path.Base(resolved.Path)
+	if strings.HasPrefix(entry.Name, "@") && !strings.HasPrefix(filename, "@") {
+		filename = strings.SplitN(entry.Name, "/", 2)[0] + "-" + filename
+	}
+
+	return filepath.Join(mirrorDir, filename)
+}
+
+// berryArchives returns the yarn v2+ cache archives of an npm entry. Only npm
+// archives are named after the package version. Patched packages share it
+// but are cached under their patch hash, so no archives are returned for
+// them.
+func berryArchives(cacheDir string, entry LockfileEntry) ([]string, error) {
+	if entry.Resolution != "" && !strings.HasPrefix(entry.Resolution, entry.Name+"@npm:") {
+		return nil, nil
+	}
+
+	slug := strings.Replace(entry.Name, "/", "-", 1)
+	return filepath.Glob(filepath.Join(cacheDir, fmt.Sprintf("%s-npm-%s-*.zip", slug, entry.Version)))
+}
+
+func sumFile(path string, h hash.Hash) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
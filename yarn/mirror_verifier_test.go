@@ -0,0 +1,240 @@
+package yarn_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMirrorVerifier(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		verifier   yarn.MirrorVerifier
+	)
+
+	sha512Integrity := func(content string) string {
+		sum := sha512.Sum512([]byte(content))
+		return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		verifier = yarn.NewMirrorVerifier()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("when the application uses a yarn v1 offline mirror", func() {
+		var entries []yarn.LockfileEntry
+
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc"), []byte(`yarn-offline-mirror "./mirror"
+`), 0644)).To(Succeed())
+
+			mirror := filepath.Join(workingDir, "mirror")
+			Expect(os.MkdirAll(mirror, os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(mirror, "lodash-4.17.15.tgz"), []byte("lodash"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(mirror, "@babel-core-7.8.3.tgz"), []byte("tampered"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(mirror, "leftpad-1.3.0.tgz"), []byte("leftpad"), 0644)).To(Succeed())
+
+			leftpadSum := sha1.Sum([]byte("leftpad"))
+
+			entries = []yarn.LockfileEntry{
+				{
+					Name:      "lodash",
+					Version:   "4.17.15",
+					Resolved:  "https://registry.yarnpkg.com/lodash/-/lodash-4.17.15.tgz#abc",
+					Integrity: sha512Integrity("lodash"),
+				},
+				{
+					Name:      "@babel/core",
+					Version:   "7.8.3",
+					Resolved:  "https://registry.yarnpkg.com/@babel/core/-/core-7.8.3.tgz",
+					Integrity: sha512Integrity("@babel/core"),
+				},
+				{
+					Name:     "leftpad",
+					Version:  "1.3.0",
+					Resolved: "https://registry.yarnpkg.com/leftpad/-/leftpad-1.3.0.tgz#" + hex.EncodeToString(leftpadSum[:]),
+				},
+				{
+					Name:      "missing",
+					Version:   "1.0.0",
+					Resolved:  "https://registry.yarnpkg.com/missing/-/missing-1.0.0.tgz",
+					Integrity: sha512Integrity("missing"),
+				},
+			}
+		})
+
+		it("reports the tarballs that do not match their integrity", func() {
+			mismatches, err := verifier.Verify(workingDir, entries)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mismatches).To(Equal([]yarn.IntegrityMismatch{
+				{
+					Name:     "@babel/core",
+					Version:  "7.8.3",
+					Path:     filepath.Join("mirror", "@babel-core-7.8.3.tgz"),
+					Expected: sha512Integrity("@babel/core"),
+					Actual:   sha512Integrity("tampered"),
+				},
+			}))
+		})
+
+		context("when the resolved URL fragment does not match", func() {
+			it.Before(func() {
+				entries[2].Resolved = "https://registry.yarnpkg.com/leftpad/-/leftpad-1.3.0.tgz#0000000000000000000000000000000000000000"
+			})
+
+			it("reports the tarball", func() {
+				mismatches, err := verifier.Verify(workingDir, entries)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mismatches).To(HaveLen(2))
+				Expect(mismatches[1].Name).To(Equal("leftpad"))
+				Expect(mismatches[1].Expected).To(Equal("sha1-0000000000000000000000000000000000000000"))
+			})
+		})
+	})
+
+	context("when the application uses a yarn v2+ cache", func() {
+		var entries []yarn.LockfileEntry
+
+		it.Before(func() {
+			cache := filepath.Join(workingDir, ".yarn", "cache")
+			Expect(os.MkdirAll(cache, os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cache, "lodash-npm-4.17.21-6382451519-eb835a2e51.zip"), []byte("lodash"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cache, "@types-node-npm-13.7.0-1a2b3c4d5e-0123456789.zip"), []byte("tampered"), 0644)).To(Succeed())
+
+			lodashSum := sha512.Sum512([]byte("lodash"))
+			nodeSum := sha512.Sum512([]byte("@types/node"))
+
+			entries = []yarn.LockfileEntry{
+				{
+					Name:     "lodash",
+					Version:  "4.17.21",
+					Checksum: "10c0/" + hex.EncodeToString(lodashSum[:]),
+				},
+				{
+					Name:     "@types/node",
+					Version:  "13.7.0",
+					Checksum: hex.EncodeToString(nodeSum[:]),
+				},
+			}
+		})
+
+		it("reports the archives that do not match their checksum", func() {
+			tamperedSum := sha512.Sum512([]byte("tampered"))
+			nodeSum := sha512.Sum512([]byte("@types/node"))
+
+			mismatches, err := verifier.Verify(workingDir, entries)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mismatches).To(Equal([]yarn.IntegrityMismatch{
+				{
+					Name:     "@types/node",
+					Version:  "13.7.0",
+					Path:     filepath.Join(".yarn", "cache", "@types-node-npm-13.7.0-1a2b3c4d5e-0123456789.zip"),
+					Expected: "sha512-" + hex.EncodeToString(nodeSum[:]),
+					Actual:   "sha512-" + hex.EncodeToString(tamperedSum[:]),
+				},
+			}))
+		})
+
+		context("when a package is installed under an alias", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, "yarn.lock"), []byte(`__metadata:
+  version: 6
+  cacheKey: 8
+
+"string-width-cjs@npm:string-width@^4.2.0":
+  version: 4.2.3
+  resolution: "string-width@npm:4.2.3"
+  checksum: `+hex.EncodeToString(make([]byte, 64))+`
+  languageName: node
+  linkType: hard
+`), 0644)).To(Succeed())
+
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarn", "cache", "string-width-npm-4.2.3-e52c10dc3f-e52c10dc3f.zip"), []byte("tampered"), 0644)).To(Succeed())
+
+				var err error
+				entries, err = yarn.NewLockfileParser().Parse(filepath.Join(workingDir, "yarn.lock"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("verifies the archive of the resolved package", func() {
+				tamperedSum := sha512.Sum512([]byte("tampered"))
+
+				mismatches, err := verifier.Verify(workingDir, entries)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mismatches).To(Equal([]yarn.IntegrityMismatch{
+					{
+						Name:     "string-width",
+						Version:  "4.2.3",
+						Path:     filepath.Join(".yarn", "cache", "string-width-npm-4.2.3-e52c10dc3f-e52c10dc3f.zip"),
+						Expected: "sha512-" + hex.EncodeToString(make([]byte, 64)),
+						Actual:   "sha512-" + hex.EncodeToString(tamperedSum[:]),
+					},
+				}))
+			})
+		})
+
+		context("when a package is patched", func() {
+			it.Before(func() {
+				entries = []yarn.LockfileEntry{
+					{
+						Name:       "lodash",
+						Version:    "4.17.21",
+						Resolution: "lodash@patch:lodash@npm%3A4.17.21#./patches/lodash.patch::version=4.17.21&hash=abc123",
+						Checksum:   "some-patched-checksum",
+					},
+				}
+			})
+
+			it("does not compare the unpatched archive", func() {
+				mismatches, err := verifier.Verify(workingDir, entries)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mismatches).To(BeEmpty())
+			})
+		})
+
+		context("when the cache folder is configured", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("cacheFolder: ./other-cache\n"), 0644)).To(Succeed())
+			})
+
+			it("reads archives from that folder", func() {
+				mismatches, err := verifier.Verify(workingDir, entries)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mismatches).To(BeEmpty())
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the .yarnrc.yml is malformed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := verifier.Verify(workingDir, nil)
+				Expect(err).To(MatchError(ContainSubstring("failed to verify package archives: failed to parse .yarnrc.yml:")))
+			})
+		})
+	})
+}
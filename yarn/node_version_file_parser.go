@@ -0,0 +1,98 @@
+package yarn
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+// ltsCodenames maps the codenames of node LTS release lines, as used in
+// aliases such as lts/erbium, to their major version.
+var ltsCodenames = map[string]string{
+	"argon":    "4",
+	"boron":    "6",
+	"carbon":   "8",
+	"dubnium":  "10",
+	"erbium":   "12",
+	"fermium":  "14",
+	"gallium":  "16",
+	"hydrogen": "18",
+	"iron":     "20",
+	"jod":      "22",
+}
+
+// NodeVersionFileParser reads the node version pinned in an .nvmrc or
+// .node-version file.
+type NodeVersionFileParser struct{}
+
+func NewNodeVersionFileParser() NodeVersionFileParser {
+	return NodeVersionFileParser{}
+}
+
+// ParseVersion returns the version in the file as a version constraint. It
+// returns an empty version when the file does not exist or names an alias
+// that cannot be expressed as a constraint, such as lts/*, which depends on
+// the releases available at the time, or system and iojs, which do not name a
+// node release at all.
+func (p NodeVersionFileParser) ParseVersion(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+	defer file.Close()
+
+	var version string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = strings.TrimSpace(line[:index])
+		}
+
+		if line != "" {
+			version = line
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return normalizeNodeVersion(version), nil
+}
+
+// normalizeNodeVersion turns the versions and aliases understood by nvm and
+// similar version managers into semver constraints. Values that are neither
+// a version nor a known alias add no requirement.
+func normalizeNodeVersion(version string) string {
+	version = strings.ToLower(version)
+
+	switch version {
+	case "node", "stable", "latest", "current":
+		return "*"
+	}
+
+	if strings.HasPrefix(version, "lts/") {
+		if major, ok := ltsCodenames[strings.TrimPrefix(version, "lts/")]; ok {
+			return major + ".*"
+		}
+
+		return ""
+	}
+
+	version = npmRange(strings.TrimPrefix(version, "v"))
+
+	_, err := semver.NewConstraint(version)
+	if err != nil {
+		return ""
+	}
+
+	return version
+}
@@ -0,0 +1,86 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testNodeVersionFileParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path   string
+		parser yarn.NodeVersionFileParser
+	)
+
+	it.Before(func() {
+		workingDir, err := ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(workingDir, ".nvmrc")
+
+		parser = yarn.NewNodeVersionFileParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+	})
+
+	context("ParseVersion", func() {
+		it("returns versions as constraints and normalizes aliases", func() {
+			for content, expected := range map[string]string{
+				"v12.16.1\n":              "12.16.1",
+				"12\n":                    "12",
+				"\n# pinned\n10.19 # LTS": "10.19",
+				"lts/erbium":              "12.*",
+				"lts/Fermium\n":           "14.*",
+				"node":                    "*",
+				"stable":                  "*",
+				"lts/*":                   "",
+				"lts/unknown":             "",
+				"lts/-1":                  "",
+				"system":                  "",
+				"iojs":                    "",
+				"node/12":                 "",
+				">=12 <15":                ">=12, <15",
+				"^10 || >= 12 < 15":       "^10 || >=12, <15",
+				"10.0.0 - 12.0.0":         "10.0.0 - 12.0.0",
+				"":                        "",
+			} {
+				Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(Equal(expected), content)
+			}
+		})
+
+		context("when the file does not exist", func() {
+			it("returns an empty version", func() {
+				version, err := parser.ParseVersion(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the file cannot be read", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(path, []byte("12"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseVersion(path)
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+		})
+	})
+}
@@ -5,26 +5,41 @@ import (
 	"os"
 )
 
+type PackageJSON struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Engines struct {
+		Node string `json:"node"`
+	} `json:"engines"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
 type PackageJSONParser struct{}
 
 func NewPackageJSONParser() PackageJSONParser {
 	return PackageJSONParser{}
 }
 
-func (p PackageJSONParser) ParseVersion(path string) (string, error) {
+func (p PackageJSONParser) Parse(path string) (PackageJSON, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return PackageJSON{}, err
 	}
 	defer file.Close()
 
-	var pkg struct {
-		Engines struct {
-			Node string `json:"node"`
-		} `json:"engines"`
+	var pkg PackageJSON
+	err = json.NewDecoder(file).Decode(&pkg)
+	if err != nil {
+		return PackageJSON{}, err
 	}
 
-	err = json.NewDecoder(file).Decode(&pkg)
+	return pkg, nil
+}
+
+func (p PackageJSONParser) ParseVersion(path string) (string, error) {
+	pkg, err := p.Parse(path)
 	if err != nil {
 		return "", err
 	}
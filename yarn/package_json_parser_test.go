@@ -28,6 +28,12 @@ func testPackageJSONParser(t *testing.T, context spec.G, it spec.S) {
 			_, err = file.WriteString(`{
 				"engines": {
 					"node": "1.2.3"
+				},
+				"dependencies": {
+					"leftpad": "^1.0.0"
+				},
+				"devDependencies": {
+					"mocha": "^7.0.0"
 				}
 			}`)
 			Expect(err).NotTo(HaveOccurred())
@@ -41,6 +47,14 @@ func testPackageJSONParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(os.RemoveAll(path)).To(Succeed())
 		})
 
+		it("parses a package.json file", func() {
+			pkg, err := parser.Parse(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pkg.Engines.Node).To(Equal("1.2.3"))
+			Expect(pkg.Dependencies).To(Equal(map[string]string{"leftpad": "^1.0.0"}))
+			Expect(pkg.DevDependencies).To(Equal(map[string]string{"mocha": "^7.0.0"}))
+		})
+
 		it("parses the node engine version from a package.json file", func() {
 			version, err := parser.ParseVersion(path)
 			Expect(err).NotTo(HaveOccurred())
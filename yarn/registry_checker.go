@@ -0,0 +1,149 @@
+package yarn
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+type RegistryViolation struct {
+	Name    string
+	Version string
+	Source  string
+	Host    string
+	Line    int
+}
+
+func (v RegistryViolation) String() string {
+	return fmt.Sprintf("yarn.lock line %d: %s@%s from %s (%s)", v.Line, v.Name, v.Version, v.Host, v.Source)
+}
+
+// RegistryChecker ensures that every package in yarn.lock is resolved from an
+// approved registry host.
+type RegistryChecker struct{}
+
+func NewRegistryChecker() RegistryChecker {
+	return RegistryChecker{}
+}
+
+// Check returns the lockfile entries resolved from hosts missing from the
+// allowlist. Allowlist entries are host names, optionally with a port, a
+// registry URL, or a shell pattern such as *.example.com. Workspace, file,
+// link, portal, patch and exec packages never leave the application and are
+// not checked.
+func (c RegistryChecker) Check(workingDir string, entries []LockfileEntry, allowed []string) ([]RegistryViolation, error) {
+	config, err := NewYarnrcParser().Parse(workingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check registries: %w", err)
+	}
+
+	var patterns []string
+	for _, a := range allowed {
+		if u, err := url.Parse(a); err == nil && u.Host != "" {
+			a = u.Host
+		}
+
+		patterns = append(patterns, strings.ToLower(a))
+	}
+
+	var violations []RegistryViolation
+	for _, entry := range entries {
+		source := entryPackageSource(entry, config.Berry)
+		if source == "" {
+			continue
+		}
+
+		host := sourceHost(source)
+		if hostAllowed(patterns, host) {
+			continue
+		}
+
+		if host == "" {
+			host = "unknown host"
+		}
+
+		violations = append(violations, RegistryViolation{
+			Name:    entry.Name,
+			Version: entry.Version,
+			Source:  source,
+			Host:    host,
+			Line:    entry.Line,
+		})
+	}
+
+	return violations, nil
+}
+
+// entryPackageSource returns the URL an entry is fetched from, or an empty
+// string for packages that come from the application itself.
+func entryPackageSource(entry LockfileEntry, berry BerryYarnConfig) string {
+	if entry.Resolution == "" {
+		if strings.HasPrefix(entry.Resolved, "file:") {
+			return ""
+		}
+
+		return entry.Resolved
+	}
+
+	reference := strings.TrimPrefix(entry.Resolution, entry.Name+"@")
+
+	protocol := ""
+	if index := strings.Index(reference, ":"); index >= 0 {
+		protocol = reference[:index]
+	}
+
+	switch protocol {
+	case "npm":
+		return strings.TrimSuffix(berry.Registry(entry.Name), "/") + "/" + entry.Name
+	case "workspace", "file", "link", "portal", "patch", "exec":
+		return ""
+	case "github":
+		return "https://github.com/" + strings.TrimPrefix(reference, "github:")
+	default:
+		return reference
+	}
+}
+
+// sourceHost returns the host of a source URL, including scp-like git
+// sources such as git@github.com:owner/repo.git.
+func sourceHost(source string) string {
+	u, err := url.Parse(source)
+	if err == nil && u.Host != "" {
+		return strings.ToLower(u.Host)
+	}
+
+	if index := strings.Index(source, ":"); index > 0 {
+		host := source[:index]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+
+		if strings.Contains(host, ".") {
+			return strings.ToLower(host)
+		}
+	}
+
+	return ""
+}
+
+func hostAllowed(patterns []string, host string) bool {
+	if host == "" {
+		return false
+	}
+
+	hostname := host
+	if index := strings.LastIndex(host, ":"); index >= 0 {
+		hostname = host[:index]
+	}
+
+	for _, pattern := range patterns {
+		for _, candidate := range []string{host, hostname} {
+			if match, err := path.Match(pattern, candidate); err == nil && match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,138 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistryChecker(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		checker    yarn.RegistryChecker
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		checker = yarn.NewRegistryChecker()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("when the lockfile was written by yarn v1", func() {
+		it("reports packages resolved from hosts missing from the allowlist", func() {
+			violations, err := checker.Check(workingDir, []yarn.LockfileEntry{
+				{Name: "lodash", Version: "4.17.15", Resolved: "https://npm.example.com/lodash/-/lodash-4.17.15.tgz#abc", Line: 5},
+				{Name: "leftpad", Version: "1.3.0", Resolved: "https://registry.npmjs.org/leftpad/-/leftpad-1.3.0.tgz", Line: 10},
+				{Name: "forked", Version: "1.0.0", Resolved: "git+ssh://git@github.com/some-org/forked.git#abc", Line: 15},
+				{Name: "mirrored", Version: "2.0.0", Resolved: "https://eu.mirror.example.com:8443/mirrored-2.0.0.tgz", Line: 20},
+				{Name: "local", Version: "0.0.1", Resolved: "file:../local", Line: 25},
+				{Name: "unresolved", Version: "0.0.2", Line: 30},
+			}, []string{"https://NPM.example.com/", "*.mirror.example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(violations).To(Equal([]yarn.RegistryViolation{
+				{
+					Name:    "leftpad",
+					Version: "1.3.0",
+					Source:  "https://registry.npmjs.org/leftpad/-/leftpad-1.3.0.tgz",
+					Host:    "registry.npmjs.org",
+					Line:    10,
+				},
+				{
+					Name:    "forked",
+					Version: "1.0.0",
+					Source:  "git+ssh://git@github.com/some-org/forked.git#abc",
+					Host:    "github.com",
+					Line:    15,
+				},
+			}))
+		})
+	})
+
+	context("when the lockfile was written by yarn v2+", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte(`npmRegistryServer: "https://npm.example.com"
+npmScopes:
+  internal:
+    npmRegistryServer: "https://internal.example.com"
+`), 0644)).To(Succeed())
+		})
+
+		it("resolves npm packages to the configured registries", func() {
+			violations, err := checker.Check(workingDir, []yarn.LockfileEntry{
+				{Name: "lodash", Version: "4.17.21", Resolution: "lodash@npm:4.17.21", Line: 5},
+				{Name: "@internal/tools", Version: "1.0.0", Resolution: "@internal/tools@npm:1.0.0", Line: 10},
+				{Name: "some-app", Version: "0.0.0-use.local", Resolution: "some-app@workspace:.", Line: 15},
+				{Name: "forked", Version: "1.0.0", Resolution: "forked@github:some-org/forked#commit=abc", Line: 20},
+				{Name: "tarball", Version: "1.0.0", Resolution: "tarball@https://cdn.example.org/tarball-1.0.0.tgz", Line: 25},
+			}, []string{"npm.example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(violations).To(Equal([]yarn.RegistryViolation{
+				{
+					Name:    "@internal/tools",
+					Version: "1.0.0",
+					Source:  "https://internal.example.com/@internal/tools",
+					Host:    "internal.example.com",
+					Line:    10,
+				},
+				{
+					Name:    "forked",
+					Version: "1.0.0",
+					Source:  "https://github.com/some-org/forked#commit=abc",
+					Host:    "github.com",
+					Line:    20,
+				},
+				{
+					Name:    "tarball",
+					Version: "1.0.0",
+					Source:  "https://cdn.example.org/tarball-1.0.0.tgz",
+					Host:    "cdn.example.org",
+					Line:    25,
+				},
+			}))
+		})
+
+		it("resolves aliased npm packages as the package they point to", func() {
+			violations, err := checker.Check(workingDir, []yarn.LockfileEntry{
+				{Name: "string-width", Alias: "string-width-cjs", Version: "4.2.3", Resolution: "string-width@npm:4.2.3", Line: 5},
+				{Name: "@internal/tools", Alias: "tools-alias", Version: "1.0.0", Resolution: "@internal/tools@npm:1.0.0", Line: 10},
+			}, []string{"npm.example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(violations).To(Equal([]yarn.RegistryViolation{
+				{
+					Name:    "@internal/tools",
+					Version: "1.0.0",
+					Source:  "https://internal.example.com/@internal/tools",
+					Host:    "internal.example.com",
+					Line:    10,
+				},
+			}))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the .yarnrc.yml is malformed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := checker.Check(workingDir, nil, []string{"npm.example.com"})
+				Expect(err).To(MatchError(ContainSubstring("failed to check registries: failed to parse .yarnrc.yml:")))
+			})
+		})
+	})
+}
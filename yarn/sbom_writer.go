@@ -0,0 +1,369 @@
+package yarn
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/packit"
+)
+
+const (
+	CycloneDXFilename = "sbom.cdx.json"
+	SPDXFilename      = "sbom.spdx.json"
+)
+
+// SBOMWriter generates CycloneDX and SPDX documents describing the packages
+// resolved in yarn.lock.
+type SBOMWriter struct {
+	packageJSONParser PackageJSONParser
+}
+
+func NewSBOMWriter() SBOMWriter {
+	return SBOMWriter{
+		packageJSONParser: NewPackageJSONParser(),
+	}
+}
+
+func (w SBOMWriter) Generate(workingDir, layerPath string, entries []LockfileEntry, info packit.BuildpackInfo, created time.Time) error {
+	pkg, err := w.packageJSONParser.Parse(filepath.Join(workingDir, "package.json"))
+	if err != nil {
+		return fmt.Errorf("failed to generate SBOM: %w", err)
+	}
+
+	packages := resolveDependencyGraph(pkg, entries)
+
+	cyclonedx, err := json.MarshalIndent(newCycloneDXDocument(pkg, packages, info, created), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = writeSBOMFile(filepath.Join(layerPath, CycloneDXFilename), cyclonedx)
+	if err != nil {
+		return err
+	}
+
+	spdx, err := json.MarshalIndent(newSPDXDocument(pkg, packages, info, created), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeSBOMFile(filepath.Join(layerPath, SPDXFilename), spdx)
+}
+
+func writeSBOMFile(path string, content []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// packageURL returns the purl identifier of an npm package, for example
+// pkg:npm/%40babel/core@7.8.3.
+func packageURL(name, version string) string {
+	return fmt.Sprintf("pkg:npm/%s@%s", strings.Replace(name, "@", "%40", 1), url.PathEscape(version))
+}
+
+type integrityHash struct {
+	algorithm string
+	digest    string
+}
+
+// parseIntegrity converts a subresource integrity string such as
+// sha512-<base64> into the hex digests used by SBOM formats.
+func parseIntegrity(integrity string) []integrityHash {
+	var hashes []integrityHash
+	for _, field := range strings.Fields(integrity) {
+		parts := strings.SplitN(field, "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		digest, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			continue
+		}
+
+		hashes = append(hashes, integrityHash{
+			algorithm: strings.ToUpper(parts[0]),
+			digest:    hex.EncodeToString(digest),
+		})
+	}
+
+	return hashes
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDXComponent struct {
+	Type    string          `json:"type"`
+	BOMRef  string          `json:"bom-ref,omitempty"`
+	Group   string          `json:"group,omitempty"`
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Scope   string          `json:"scope,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+	PURL    string          `json:"purl,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+func newCycloneDXDocument(pkg PackageJSON, packages []*graphPackage, info packit.BuildpackInfo, created time.Time) cycloneDXDocument {
+	document := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.2",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: info.Name, Version: info.Version}},
+			Component: cycloneDXComponent{
+				Type:    "application",
+				Name:    pkg.Name,
+				Version: pkg.Version,
+			},
+		},
+		Components:   []cycloneDXComponent{},
+		Dependencies: []cycloneDXDependency{},
+	}
+
+	for _, p := range packages {
+		component := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  p.ref,
+			Name:    p.entry.Name,
+			Version: p.entry.Version,
+			Scope:   "required",
+			PURL:    p.purl,
+		}
+
+		if parts := strings.SplitN(p.entry.Name, "/", 2); len(parts) == 2 && strings.HasPrefix(p.entry.Name, "@") {
+			component.Group, component.Name = parts[0], parts[1]
+		}
+
+		if p.scope == scopeDevelopment {
+			component.Scope = "optional"
+		}
+
+		for _, hash := range parseIntegrity(p.entry.Integrity) {
+			component.Hashes = append(component.Hashes, cycloneDXHash{
+				Algorithm: strings.Replace(hash.algorithm, "SHA", "SHA-", 1),
+				Content:   hash.digest,
+			})
+		}
+
+		document.Components = append(document.Components, component)
+
+		dependsOn := p.dependencies
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+
+		document.Dependencies = append(document.Dependencies, cycloneDXDependency{
+			Ref:       p.ref,
+			DependsOn: dependsOn,
+		})
+	}
+
+	return document
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func newSPDXDocument(pkg PackageJSON, packages []*graphPackage, info packit.BuildpackInfo, created time.Time) spdxDocument {
+	const rootID = "SPDXRef-Package-root"
+
+	hash := sha256.New()
+	for _, p := range packages {
+		fmt.Fprintln(hash, p.purl)
+	}
+
+	document := spdxDocument{
+		SPDXVersion:       "SPDX-2.2",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              pkg.Name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", url.PathEscape(pkg.Name), hex.EncodeToString(hash.Sum(nil))),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{fmt.Sprintf("Tool: %s-%s", info.Name, info.Version)},
+		},
+		Packages: []spdxPackage{
+			{
+				SPDXID:           rootID,
+				Name:             pkg.Name,
+				VersionInfo:      pkg.Version,
+				DownloadLocation: "NOASSERTION",
+				LicenseConcluded: "NOASSERTION",
+				LicenseDeclared:  "NOASSERTION",
+				CopyrightText:    "NOASSERTION",
+			},
+		},
+		Relationships: []spdxRelationship{
+			{
+				SPDXElementID:      "SPDXRef-DOCUMENT",
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: rootID,
+			},
+		},
+	}
+
+	ids := map[string]string{}
+	for i, p := range packages {
+		ids[p.ref] = fmt.Sprintf("SPDXRef-Package-%d", i+1)
+	}
+
+	direct := map[string]bool{}
+	for _, dependencies := range []map[string]string{pkg.DevDependencies, pkg.Dependencies, pkg.OptionalDependencies} {
+		for name, rng := range dependencies {
+			direct[name+"@"+rng] = true
+			direct[name+"@npm:"+rng] = true
+		}
+	}
+
+	for _, p := range packages {
+		id := ids[p.ref]
+
+		downloadLocation := p.entry.Resolved
+		if downloadLocation == "" {
+			downloadLocation = "NOASSERTION"
+		}
+
+		spdx := spdxPackage{
+			SPDXID:           id,
+			Name:             p.entry.Name,
+			VersionInfo:      p.entry.Version,
+			DownloadLocation: downloadLocation,
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+			CopyrightText:    "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{
+				{
+					ReferenceCategory: "PACKAGE_MANAGER",
+					ReferenceType:     "purl",
+					ReferenceLocator:  p.purl,
+				},
+			},
+		}
+
+		for _, hash := range parseIntegrity(p.entry.Integrity) {
+			spdx.Checksums = append(spdx.Checksums, spdxChecksum{
+				Algorithm:     hash.algorithm,
+				ChecksumValue: hash.digest,
+			})
+		}
+
+		document.Packages = append(document.Packages, spdx)
+
+		for _, descriptor := range p.entry.Descriptors {
+			if !direct[descriptor] {
+				continue
+			}
+
+			if p.scope == scopeDevelopment {
+				document.Relationships = append(document.Relationships, spdxRelationship{
+					SPDXElementID:      id,
+					RelationshipType:   "DEV_DEPENDENCY_OF",
+					RelatedSPDXElement: rootID,
+				})
+			} else {
+				document.Relationships = append(document.Relationships, spdxRelationship{
+					SPDXElementID:      rootID,
+					RelationshipType:   "DEPENDS_ON",
+					RelatedSPDXElement: id,
+				})
+			}
+
+			break
+		}
+
+		for _, dependency := range p.dependencies {
+			document.Relationships = append(document.Relationships, spdxRelationship{
+				SPDXElementID:      id,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: ids[dependency],
+			})
+		}
+	}
+
+	return document
+}
@@ -0,0 +1,238 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/cloudfoundry/packit"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSBOMWriter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		layerPath  string
+		entries    []yarn.LockfileEntry
+		info       packit.BuildpackInfo
+		created    time.Time
+		writer     yarn.SBOMWriter
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		layerPath, err = ioutil.TempDir("", "sbom")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(workingDir, "package.json"), []byte(`{
+			"name": "some-app",
+			"version": "1.0.0",
+			"dependencies": {
+				"@scope/prod": "^1.0.0"
+			},
+			"devDependencies": {
+				"dev": "^2.0.0"
+			}
+		}`), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		entries = []yarn.LockfileEntry{
+			{
+				Name:        "@scope/prod",
+				Descriptors: []string{"@scope/prod@^1.0.0"},
+				Version:     "1.2.3",
+				Resolved:    "https://registry.yarnpkg.com/@scope/prod/-/prod-1.2.3.tgz",
+				Integrity:   "sha1-AAECAwQFBgcICQoLDA0ODxAREhM=",
+				Dependencies: map[string]string{
+					"transitive": "~3.0.0",
+				},
+			},
+			{
+				Name:        "transitive",
+				Descriptors: []string{"transitive@~3.0.0"},
+				Version:     "3.0.1",
+			},
+			{
+				Name:        "dev",
+				Descriptors: []string{"dev@^2.0.0"},
+				Version:     "2.0.0",
+			},
+		}
+
+		info = packit.BuildpackInfo{Name: "Yarn Buildpack", Version: "some-version"}
+		created = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		writer = yarn.NewSBOMWriter()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+		Expect(os.RemoveAll(layerPath)).To(Succeed())
+	})
+
+	context("Generate", func() {
+		it("writes a CycloneDX document", func() {
+			err := writer.Generate(workingDir, layerPath, entries, info, created)
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := ioutil.ReadFile(filepath.Join(layerPath, "sbom.cdx.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(MatchJSON(`{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.2",
+				"version": 1,
+				"metadata": {
+					"timestamp": "2020-01-01T00:00:00Z",
+					"tools": [{"name": "Yarn Buildpack", "version": "some-version"}],
+					"component": {"type": "application", "name": "some-app", "version": "1.0.0"}
+				},
+				"components": [
+					{
+						"type": "library",
+						"bom-ref": "pkg:npm/%40scope/prod@1.2.3",
+						"group": "@scope",
+						"name": "prod",
+						"version": "1.2.3",
+						"scope": "required",
+						"hashes": [{"alg": "SHA-1", "content": "000102030405060708090a0b0c0d0e0f10111213"}],
+						"purl": "pkg:npm/%40scope/prod@1.2.3"
+					},
+					{
+						"type": "library",
+						"bom-ref": "pkg:npm/transitive@3.0.1",
+						"name": "transitive",
+						"version": "3.0.1",
+						"scope": "required",
+						"purl": "pkg:npm/transitive@3.0.1"
+					},
+					{
+						"type": "library",
+						"bom-ref": "pkg:npm/dev@2.0.0",
+						"name": "dev",
+						"version": "2.0.0",
+						"scope": "optional",
+						"purl": "pkg:npm/dev@2.0.0"
+					}
+				],
+				"dependencies": [
+					{"ref": "pkg:npm/%40scope/prod@1.2.3", "dependsOn": ["pkg:npm/transitive@3.0.1"]},
+					{"ref": "pkg:npm/transitive@3.0.1", "dependsOn": []},
+					{"ref": "pkg:npm/dev@2.0.0", "dependsOn": []}
+				]
+			}`))
+		})
+
+		it("writes an SPDX document", func() {
+			err := writer.Generate(workingDir, layerPath, entries, info, created)
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := ioutil.ReadFile(filepath.Join(layerPath, "sbom.spdx.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`"spdxVersion": "SPDX-2.2"`))
+			Expect(string(content)).To(ContainSubstring(`"created": "2020-01-01T00:00:00Z"`))
+			Expect(string(content)).To(ContainSubstring(`"Tool: Yarn Buildpack-some-version"`))
+			Expect(string(content)).To(ContainSubstring(`"downloadLocation": "https://registry.yarnpkg.com/@scope/prod/-/prod-1.2.3.tgz"`))
+			Expect(string(content)).To(ContainSubstring(`"checksumValue": "000102030405060708090a0b0c0d0e0f10111213"`))
+			Expect(string(content)).To(ContainSubstring(`"referenceLocator": "pkg:npm/%40scope/prod@1.2.3"`))
+			Expect(string(content)).To(MatchRegexp(`"spdxElementId": "SPDXRef-Package-root",\s+"relationshipType": "DEPENDS_ON",\s+"relatedSpdxElement": "SPDXRef-Package-1"`))
+			Expect(string(content)).To(MatchRegexp(`"spdxElementId": "SPDXRef-Package-1",\s+"relationshipType": "DEPENDS_ON",\s+"relatedSpdxElement": "SPDXRef-Package-2"`))
+			Expect(string(content)).To(MatchRegexp(`"spdxElementId": "SPDXRef-Package-3",\s+"relationshipType": "DEV_DEPENDENCY_OF",\s+"relatedSpdxElement": "SPDXRef-Package-root"`))
+		})
+
+		context("when the entries come from a yarn v2+ yarn.lock", func() {
+			it.Before(func() {
+				entries = []yarn.LockfileEntry{
+					{
+						Name:        "@scope/prod",
+						Descriptors: []string{"@scope/prod@npm:^1.0.0"},
+						Version:     "1.2.3",
+						Resolution:  "@scope/prod@npm:1.2.3",
+						Dependencies: map[string]string{
+							"transitive": "~3.0.0",
+						},
+					},
+					{
+						Name:        "transitive",
+						Descriptors: []string{"transitive@npm:~3.0.0"},
+						Version:     "3.0.1",
+						Resolution:  "transitive@npm:3.0.1",
+					},
+					{
+						Name:        "dev",
+						Descriptors: []string{"dev@npm:^2.0.0"},
+						Version:     "2.0.0",
+						Resolution:  "dev@npm:2.0.0",
+					},
+				}
+			})
+
+			it("links the npm protocol descriptors", func() {
+				err := writer.Generate(workingDir, layerPath, entries, info, created)
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layerPath, "sbom.cdx.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(MatchRegexp(`"ref": "pkg:npm/%40scope/prod@1.2.3",\s+"dependsOn": \[\s+"pkg:npm/transitive@3.0.1"`))
+				Expect(string(content)).To(MatchRegexp(`"name": "dev",\s+"version": "2.0.0",\s+"scope": "optional"`))
+			})
+		})
+
+		context("when a yarn v2+ yarn.lock lists a patched package", func() {
+			it.Before(func() {
+				entries = []yarn.LockfileEntry{
+					{
+						Name:        "typescript",
+						Descriptors: []string{"typescript@npm:^4.9.0"},
+						Version:     "4.9.5",
+						Resolution:  "typescript@npm:4.9.5",
+					},
+					{
+						Name:        "typescript",
+						Descriptors: []string{"typescript@patch:typescript@npm%3A^4.9.0#~builtin<compat/typescript>"},
+						Version:     "4.9.5",
+						Resolution:  "typescript@patch:typescript@npm%3A4.9.5#~builtin<compat/typescript>::version=4.9.5&hash=1a91c8",
+					},
+				}
+			})
+
+			it("gives each entry a unique ref and SPDX ID", func() {
+				err := writer.Generate(workingDir, layerPath, entries, info, created)
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := ioutil.ReadFile(filepath.Join(layerPath, "sbom.cdx.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`"bom-ref": "pkg:npm/typescript@4.9.5"`))
+				Expect(string(content)).To(ContainSubstring(`"bom-ref": "pkg:npm/typescript@4.9.5#2"`))
+				Expect(string(content)).To(ContainSubstring(`"ref": "pkg:npm/typescript@4.9.5#2"`))
+
+				content, err = ioutil.ReadFile(filepath.Join(layerPath, "sbom.spdx.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`"SPDXID": "SPDXRef-Package-1"`))
+				Expect(string(content)).To(ContainSubstring(`"SPDXID": "SPDXRef-Package-2"`))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the package.json cannot be parsed", func() {
+				it.Before(func() {
+					Expect(ioutil.WriteFile(filepath.Join(workingDir, "package.json"), []byte("%%%"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := writer.Generate(workingDir, layerPath, entries, info, created)
+					Expect(err).To(MatchError(ContainSubstring("failed to generate SBOM: invalid character")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package yarn
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ServiceBindingRoot returns the directory holding the service bindings given
+// to the build, as set by SERVICE_BINDING_ROOT.
+func ServiceBindingRoot() string {
+	root, ok := os.LookupEnv("SERVICE_BINDING_ROOT")
+	if !ok {
+		return "/platform/bindings"
+	}
+
+	return root
+}
+
+// findAdvisoryBinding returns the path of the first service binding below
+// root whose type is AdvisoryBindingType, or an empty string if there is none.
+func findAdvisoryBinding(root string) (string, error) {
+	bindings, err := listBindings(root)
+	if err != nil {
+		return "", err
+	}
+
+	for _, binding := range bindings {
+		content, err := ioutil.ReadFile(filepath.Join(binding, "type"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return "", err
+		}
+
+		if strings.TrimSpace(string(content)) == AdvisoryBindingType {
+			return binding, nil
+		}
+	}
+
+	return "", nil
+}
+
+// readBindingSecrets returns the value of every entry of every binding below
+// root, apart from the type and provider entries that describe the binding.
+// Advisory database bindings are skipped since they hold public data.
+func readBindingSecrets(root string) ([]string, error) {
+	bindings, err := listBindings(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []string
+	for _, binding := range bindings {
+		infos, err := ioutil.ReadDir(binding)
+		if err != nil {
+			return nil, err
+		}
+
+		kind, err := ioutil.ReadFile(filepath.Join(binding, "type"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if strings.TrimSpace(string(kind)) == AdvisoryBindingType {
+			continue
+		}
+
+		for _, info := range infos {
+			name := info.Name()
+			if info.IsDir() || name == "type" || name == "provider" || strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(binding, name))
+			if err != nil {
+				return nil, err
+			}
+
+			if secret := strings.TrimSpace(string(content)); secret != "" {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+
+	return secrets, nil
+}
+
+func listBindings(root string) ([]string, error) {
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var bindings []string
+	for _, info := range infos {
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			bindings = append(bindings, filepath.Join(root, info.Name()))
+		}
+	}
+
+	return bindings, nil
+}
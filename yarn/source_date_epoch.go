@@ -0,0 +1,42 @@
+package yarn
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// sourceDateEpoch returns the time given by the SOURCE_DATE_EPOCH environment
+// variable, if it is set. See https://reproducible-builds.org/specs/source-date-epoch/.
+func sourceDateEpoch() (time.Time, bool, error) {
+	value, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || value == "" {
+		return time.Time{}, false, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse SOURCE_DATE_EPOCH: %q is not a number of seconds", value)
+	}
+
+	return time.Unix(seconds, 0).UTC(), true, nil
+}
+
+// normalizeModTimes sets the access and modification times of every file and
+// directory below root to the given time. Symlinks are skipped as os.Chtimes
+// would follow them.
+func normalizeModTimes(root string, t time.Time) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+
+		return os.Chtimes(path, t, t)
+	})
+}
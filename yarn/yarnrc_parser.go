@@ -0,0 +1,287 @@
+package yarn
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultBerryRegistry is the registry yarn v2+ (Berry) resolves npm packages
+// from when .yarnrc.yml does not set npmRegistryServer.
+const DefaultBerryRegistry = "https://registry.yarnpkg.com"
+
+// DefaultClassicRegistry is the registry yarn v1 resolves packages from when
+// .yarnrc does not set registry.
+const DefaultClassicRegistry = "https://registry.yarnpkg.com"
+
+// YarnConfig holds the yarn settings of an application. Yarn v1 reads .yarnrc
+// and yarn v2+ (Berry) reads .yarnrc.yml, so both are kept apart and the one
+// matching the yarn version of the application applies.
+type YarnConfig struct {
+	Classic ClassicYarnConfig
+	Berry   BerryYarnConfig
+}
+
+// ClassicYarnConfig holds the .yarnrc settings used by the buildpack. Paths
+// are absolute.
+type ClassicYarnConfig struct {
+	Registry        string
+	ScopeRegistries map[string]string
+	OfflineMirror   string
+	CacheFolder     string
+	YarnPath        string
+}
+
+// BerryYarnConfig holds the .yarnrc.yml settings used by the buildpack. Paths
+// are absolute.
+type BerryYarnConfig struct {
+	NpmRegistryServer string
+	NpmScopes         map[string]string
+	CacheFolder       string
+	EnableGlobalCache bool
+	NodeLinker        string
+	YarnPath          string
+}
+
+// Registry returns the registry npm packages of the given name are resolved
+// from, honoring per-scope registries.
+func (c BerryYarnConfig) Registry(name string) string {
+	if strings.HasPrefix(name, "@") {
+		scope := strings.TrimPrefix(strings.SplitN(name, "/", 2)[0], "@")
+		if server := c.NpmScopes[scope]; server != "" {
+			return server
+		}
+	}
+
+	return c.NpmRegistryServer
+}
+
+// ProjectCache returns the cache folder inside the application, or an empty
+// string when packages are kept in the global cache instead.
+func (c BerryYarnConfig) ProjectCache() string {
+	if c.EnableGlobalCache {
+		return ""
+	}
+
+	return c.CacheFolder
+}
+
+// YarnrcParser reads the yarn configuration of an application from .yarnrc,
+// .yarnrc.yml and YARN_* environment variables. As in yarn itself,
+// environment variables take precedence over the files. Only the files in the
+// working directory are read, since the home directory of the build does not
+// belong to the application.
+type YarnrcParser struct{}
+
+func NewYarnrcParser() YarnrcParser {
+	return YarnrcParser{}
+}
+
+func (p YarnrcParser) Parse(workingDir string) (YarnConfig, error) {
+	classic, err := parseClassicYarnrc(workingDir)
+	if err != nil {
+		return YarnConfig{}, err
+	}
+
+	berry, err := parseBerryYarnrc(workingDir)
+	if err != nil {
+		return YarnConfig{}, err
+	}
+
+	return YarnConfig{Classic: classic, Berry: berry}, nil
+}
+
+// parseClassicYarnrc reads .yarnrc, which uses the yarn.lock v1 syntax of a
+// key and a value per line. Yarn v1 turns every YARN_* environment variable
+// into a setting by lowercasing it and replacing underscores with dashes, so
+// YARN_YARN_OFFLINE_MIRROR sets yarn-offline-mirror.
+func parseClassicYarnrc(workingDir string) (ClassicYarnConfig, error) {
+	settings := map[string]string{}
+
+	file, err := os.Open(filepath.Join(workingDir, ".yarnrc"))
+	if err != nil && !os.IsNotExist(err) {
+		return ClassicYarnConfig{}, err
+	}
+
+	if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			key, value := splitYarnrcLine(line)
+			settings[key] = value
+		}
+
+		if err := scanner.Err(); err != nil {
+			return ClassicYarnConfig{}, fmt.Errorf("failed to parse .yarnrc: %w", err)
+		}
+	}
+
+	for _, variable := range os.Environ() {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(strings.ToUpper(parts[0]), "YARN_") {
+			key := strings.ToLower(strings.Replace(parts[0][len("YARN_"):], "_", "-", -1))
+			settings[key] = parts[1]
+		}
+	}
+
+	config := ClassicYarnConfig{
+		Registry:        DefaultClassicRegistry,
+		ScopeRegistries: map[string]string{},
+	}
+
+	for key, value := range settings {
+		switch {
+		case key == "registry":
+			config.Registry = value
+		case key == "yarn-offline-mirror":
+			config.OfflineMirror = resolvePath(workingDir, value)
+		case key == "cache-folder":
+			config.CacheFolder = resolvePath(workingDir, value)
+		case key == "yarn-path":
+			config.YarnPath = resolvePath(workingDir, value)
+		case strings.HasPrefix(key, "@") && strings.HasSuffix(key, ":registry"):
+			config.ScopeRegistries[strings.TrimSuffix(strings.TrimPrefix(key, "@"), ":registry")] = value
+		}
+	}
+
+	return config, nil
+}
+
+// splitYarnrcLine splits a .yarnrc line into its key and value, either of
+// which may be quoted.
+func splitYarnrcLine(line string) (string, string) {
+	var key, rest string
+	if strings.HasPrefix(line, `"`) {
+		end := strings.Index(line[1:], `"`)
+		if end < 0 {
+			return strings.Trim(line, `"`), ""
+		}
+
+		key, rest = line[1:end+1], line[end+2:]
+	} else {
+		fields := strings.SplitN(line, " ", 2)
+		key = fields[0]
+		if len(fields) == 2 {
+			rest = fields[1]
+		}
+	}
+
+	value := strings.TrimSpace(rest)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	return key, value
+}
+
+// parseBerryYarnrc reads .yarnrc.yml. Yarn v2+ lets YARN_ followed by the
+// setting name in screaming snake case override each setting, so
+// YARN_NPM_REGISTRY_SERVER sets npmRegistryServer.
+func parseBerryYarnrc(workingDir string) (BerryYarnConfig, error) {
+	var rc struct {
+		CacheFolder       string `yaml:"cacheFolder"`
+		EnableGlobalCache string `yaml:"enableGlobalCache"`
+		NodeLinker        string `yaml:"nodeLinker"`
+		NpmRegistryServer string `yaml:"npmRegistryServer"`
+		NpmScopes         map[string]struct {
+			NpmRegistryServer string `yaml:"npmRegistryServer"`
+		} `yaml:"npmScopes"`
+		YarnPath string `yaml:"yarnPath"`
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(workingDir, ".yarnrc.yml"))
+	if err != nil && !os.IsNotExist(err) {
+		return BerryYarnConfig{}, err
+	}
+
+	err = yaml.Unmarshal(content, &rc)
+	if err != nil {
+		return BerryYarnConfig{}, fmt.Errorf("failed to parse .yarnrc.yml: %w", err)
+	}
+
+	for setting, value := range map[string]*string{
+		"cacheFolder":       &rc.CacheFolder,
+		"enableGlobalCache": &rc.EnableGlobalCache,
+		"nodeLinker":        &rc.NodeLinker,
+		"npmRegistryServer": &rc.NpmRegistryServer,
+		"yarnPath":          &rc.YarnPath,
+	} {
+		if override, ok := os.LookupEnv(berryEnvironmentName(setting)); ok {
+			*value = override
+		}
+	}
+
+	config := BerryYarnConfig{
+		NpmRegistryServer: DefaultBerryRegistry,
+		NpmScopes:         map[string]string{},
+		CacheFolder:       filepath.Join(workingDir, ".yarn", "cache"),
+		NodeLinker:        "pnp",
+	}
+
+	if rc.NpmRegistryServer != "" {
+		config.NpmRegistryServer = rc.NpmRegistryServer
+	}
+
+	for scope, settings := range rc.NpmScopes {
+		if settings.NpmRegistryServer != "" {
+			config.NpmScopes[scope] = settings.NpmRegistryServer
+		}
+	}
+
+	if rc.CacheFolder != "" {
+		config.CacheFolder = resolvePath(workingDir, rc.CacheFolder)
+	}
+
+	if rc.EnableGlobalCache != "" {
+		config.EnableGlobalCache, err = strconv.ParseBool(rc.EnableGlobalCache)
+		if err != nil {
+			return BerryYarnConfig{}, fmt.Errorf("failed to parse .yarnrc.yml: enableGlobalCache must be true or false, got %q", rc.EnableGlobalCache)
+		}
+	}
+
+	if rc.NodeLinker != "" {
+		config.NodeLinker = rc.NodeLinker
+	}
+
+	if rc.YarnPath != "" {
+		config.YarnPath = resolvePath(workingDir, rc.YarnPath)
+	}
+
+	return config, nil
+}
+
+// berryEnvironmentName returns the environment variable overriding a yarn v2+
+// setting, such as YARN_NPM_REGISTRY_SERVER for npmRegistryServer.
+func berryEnvironmentName(setting string) string {
+	var builder strings.Builder
+	builder.WriteString("YARN_")
+	for i, r := range setting {
+		if unicode.IsUpper(r) && i > 0 {
+			builder.WriteRune('_')
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+
+	return builder.String()
+}
+
+func resolvePath(workingDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(workingDir, path)
+}
@@ -0,0 +1,182 @@
+package yarn_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ForestEckhardt/yarn-cnb/yarn"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testYarnrcParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		parser     yarn.YarnrcParser
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = ioutil.TempDir("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		parser = yarn.NewYarnrcParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("when there is no yarn configuration", func() {
+		it("returns the yarn defaults", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(yarn.YarnConfig{
+				Classic: yarn.ClassicYarnConfig{
+					Registry:        "https://registry.yarnpkg.com",
+					ScopeRegistries: map[string]string{},
+				},
+				Berry: yarn.BerryYarnConfig{
+					NpmRegistryServer: "https://registry.yarnpkg.com",
+					NpmScopes:         map[string]string{},
+					CacheFolder:       filepath.Join(workingDir, ".yarn", "cache"),
+					NodeLinker:        "pnp",
+				},
+			}))
+		})
+	})
+
+	context("when there is a .yarnrc", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc"), []byte(`# yarn lockfile v1
+registry "https://npm.example.com"
+"@some-scope:registry" "https://scope.example.com"
+yarn-offline-mirror "./mirror"
+cache-folder /tmp/yarn-cache
+yarn-path ".yarn/releases/yarn-1.22.0.js"
+--install.frozen-lockfile true
+`), 0644)).To(Succeed())
+		})
+
+		it("parses the classic settings", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Classic).To(Equal(yarn.ClassicYarnConfig{
+				Registry:        "https://npm.example.com",
+				ScopeRegistries: map[string]string{"some-scope": "https://scope.example.com"},
+				OfflineMirror:   filepath.Join(workingDir, "mirror"),
+				CacheFolder:     "/tmp/yarn-cache",
+				YarnPath:        filepath.Join(workingDir, ".yarn", "releases", "yarn-1.22.0.js"),
+			}))
+		})
+
+		context("when YARN_* environment variables are set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("YARN_REGISTRY", "https://env.example.com")).To(Succeed())
+				Expect(os.Setenv("YARN_YARN_OFFLINE_MIRROR", "/env/mirror")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("YARN_REGISTRY")).To(Succeed())
+				Expect(os.Unsetenv("YARN_YARN_OFFLINE_MIRROR")).To(Succeed())
+			})
+
+			it("lets them override the file", func() {
+				config, err := parser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Classic.Registry).To(Equal("https://env.example.com"))
+				Expect(config.Classic.OfflineMirror).To(Equal("/env/mirror"))
+				Expect(config.Classic.CacheFolder).To(Equal("/tmp/yarn-cache"))
+			})
+		})
+	})
+
+	context("when there is a .yarnrc.yml", func() {
+		it.Before(func() {
+			Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte(`cacheFolder: ./cache
+enableGlobalCache: false
+nodeLinker: node-modules
+npmRegistryServer: "https://npm.example.com"
+npmScopes:
+  some-scope:
+    npmRegistryServer: "https://scope.example.com"
+yarnPath: .yarn/releases/yarn-3.2.0.cjs
+`), 0644)).To(Succeed())
+		})
+
+		it("parses the Berry settings", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Berry).To(Equal(yarn.BerryYarnConfig{
+				NpmRegistryServer: "https://npm.example.com",
+				NpmScopes:         map[string]string{"some-scope": "https://scope.example.com"},
+				CacheFolder:       filepath.Join(workingDir, "cache"),
+				NodeLinker:        "node-modules",
+				YarnPath:          filepath.Join(workingDir, ".yarn", "releases", "yarn-3.2.0.cjs"),
+			}))
+			Expect(config.Berry.Registry("@some-scope/some-package")).To(Equal("https://scope.example.com"))
+			Expect(config.Berry.Registry("some-package")).To(Equal("https://npm.example.com"))
+			Expect(config.Berry.ProjectCache()).To(Equal(filepath.Join(workingDir, "cache")))
+		})
+
+		context("when YARN_* environment variables are set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("YARN_NPM_REGISTRY_SERVER", "https://env.example.com")).To(Succeed())
+				Expect(os.Setenv("YARN_ENABLE_GLOBAL_CACHE", "true")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("YARN_NPM_REGISTRY_SERVER")).To(Succeed())
+				Expect(os.Unsetenv("YARN_ENABLE_GLOBAL_CACHE")).To(Succeed())
+			})
+
+			it("lets them override the file", func() {
+				config, err := parser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Berry.NpmRegistryServer).To(Equal("https://env.example.com"))
+				Expect(config.Berry.EnableGlobalCache).To(BeTrue())
+				Expect(config.Berry.ProjectCache()).To(BeEmpty())
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the .yarnrc.yml is malformed", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("%%%"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse .yarnrc.yml:")))
+			})
+		})
+
+		context("when enableGlobalCache is not a boolean", func() {
+			it.Before(func() {
+				Expect(ioutil.WriteFile(filepath.Join(workingDir, ".yarnrc.yml"), []byte("enableGlobalCache: sometimes\n"), 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(`failed to parse .yarnrc.yml: enableGlobalCache must be true or false, got "sometimes"`))
+			})
+		})
+
+		context("when the .yarnrc cannot be read", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, ".yarnrc"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse .yarnrc:")))
+			})
+		})
+	})
+}